@@ -1,3 +1,315 @@
 package client
 
-func NewClient(serverUrl string) {}
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/alphaqiu/ginrpc"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultPrefix = "/api"
+	MIMEJSON      = "application/json; charset=utf-8"
+)
+
+type Option func(c *Client)
+
+// WithPrefix 服务端配置的 Config.UrlPrefix，默认为 /api
+func WithPrefix(prefix string) Option {
+	return func(c *Client) {
+		c.prefix = prefix
+	}
+}
+
+// WithConfig 使用服务端的配置, 保证 UrlPrefix 和方法前缀规则与服务端一致
+func WithConfig(cnf *ginrpc.Config) Option {
+	return func(c *Client) {
+		c.prefix = cnf.UrlPrefix
+		c.parser = ginrpc.NewRouteParser(cnf)
+	}
+}
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithHeader 每次请求都会携带的公共Header
+func WithHeader(header http.Header) Option {
+	return func(c *Client) {
+		for k, vs := range header {
+			for _, v := range vs {
+				c.header.Add(k, v)
+			}
+		}
+	}
+}
+
+type Client struct {
+	serverUrl  string
+	prefix     string
+	httpClient *http.Client
+	header     http.Header
+	parser     *ginrpc.RouteParser
+}
+
+func NewClient(serverUrl string, options ...Option) *Client {
+	c := &Client{
+		serverUrl:  strings.TrimRight(serverUrl, "/"),
+		prefix:     DefaultPrefix,
+		httpClient: http.DefaultClient,
+		header:     http.Header{},
+		parser:     ginrpc.NewRouteParser(nil),
+	}
+
+	for _, option := range options {
+		option(c)
+	}
+
+	return c
+}
+
+// Service 返回绑定在服务端的资源，resource 为服务结构体名称，version 为 ResourceVersion 返回的版本
+func (c *Client) Service(resource, version string) *Service {
+	if len(version) == 0 {
+		version = "v0"
+	}
+	return &Service{client: c, resource: resource, version: version}
+}
+
+// Invoke 调用 /{prefix}/{version}/{resource}/{action} 路由, 参数与 Do 一致
+func (c *Client) Invoke(ctx context.Context, method, version, resource, action string, result interface{}, params ...interface{}) error {
+	return c.Do(ctx, method, "/"+strings.Join([]string{version, resource, action}, "/"), result, params...)
+}
+
+// Do 调用 prefix 之后的 path 路由.
+// params 的规则与服务端方法入參一致: http.Header 作为请求头，类型名称后缀为Path的结构体作为路径参数，
+// 类型名称后缀为Query的结构体作为url参数，其它结构体作为请求体。result 必须是指针，用于接收响应中的result字段，可以为nil。
+// 路径参数替换 path 中同名的 :param, 没有声明的按顺序追加在 path 之后。服务端返回的错误以 *Error 返回。
+func (c *Client) Do(ctx context.Context, method, path string, result interface{}, params ...interface{}) error {
+	var (
+		pathParams []pathParam
+		query      = url.Values{}
+		header     = http.Header{}
+		body       interface{}
+	)
+
+	for _, param := range params {
+		if param == nil {
+			continue
+		}
+
+		if h, ok := param.(http.Header); ok {
+			for k, vs := range h {
+				for _, v := range vs {
+					header.Add(k, v)
+				}
+			}
+			continue
+		}
+
+		pt := reflect.TypeOf(param)
+		if pt.Kind() == reflect.Ptr {
+			pt = pt.Elem()
+		}
+
+		if pt.Kind() != reflect.Struct {
+			return errors.Errorf("unsupported param type: %s", pt)
+		}
+
+		if strings.HasSuffix(pt.Name(), "Path") {
+			values, err := encodePath(param)
+			if err != nil {
+				return errors.Wrapf(err, "encode path %s", pt)
+			}
+			pathParams = append(pathParams, values...)
+			continue
+		}
+
+		if strings.HasSuffix(pt.Name(), "Query") {
+			if err := encodeForm(param, query); err != nil {
+				return errors.Wrapf(err, "encode query %s", pt)
+			}
+			continue
+		}
+
+		if body != nil {
+			return errors.Errorf("duplicate body param: %s", pt)
+		}
+		body = param
+	}
+
+	var reader io.Reader
+	if body != nil {
+		// 服务端GET请求的body参数以Form的方式绑定
+		if method == http.MethodGet {
+			if err := encodeForm(body, query); err != nil {
+				return errors.Wrap(err, "encode body as query")
+			}
+		} else {
+			data, err := json.Marshal(body)
+			if err != nil {
+				return errors.Wrap(err, "encode body")
+			}
+			reader = bytes.NewReader(data)
+			header.Set("Content-Type", MIMEJSON)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.url(path, pathParams, query), reader)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	for k, vs := range c.header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+
+	for k, vs := range header {
+		req.Header.Del(k)
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+
+	return decodeResponse(resp, result)
+}
+
+func (c *Client) url(path string, params []pathParam, query url.Values) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, param := range params {
+		replaced := false
+		for i, segment := range segments {
+			if segment == ":"+param.name {
+				segments[i] = param.value
+				replaced = true
+				break
+			}
+		}
+
+		if !replaced {
+			segments = append(segments, param.value)
+		}
+	}
+
+	if prefix := strings.Trim(c.prefix, "/"); len(prefix) > 0 {
+		segments = append([]string{prefix}, segments...)
+	}
+
+	u := c.serverUrl + "/" + strings.Join(segments, "/")
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+// Service 绑定在服务端的一个资源，通过Go方法名称调用对应的action
+type Service struct {
+	client   *Client
+	resource string
+	version  string
+	routes   map[string]ginrpc.RouteSpec
+}
+
+// WithRoutes 使用服务声明的 ginrpc.ResourceRoutes 覆盖路由
+func (s *Service) WithRoutes(routes map[string]ginrpc.RouteSpec) *Service {
+	s.routes = routes
+	return s
+}
+
+// Call 使用与服务端相同的规则，由方法名称推导出HTTP Method和action
+func (s *Service) Call(ctx context.Context, methodName string, result interface{}, params ...interface{}) error {
+	method, resource, action := s.client.parser.Parse(s.resource, methodName)
+	method, path := s.routes[methodName].Resolve(method, s.version, resource, action)
+	return s.client.Do(ctx, method, path, result, params...)
+}
+
+type envelope struct {
+	Code    int             `json:"code"`
+	Result  json.RawMessage `json:"result"`
+	Message string          `json:"message"`
+	Error   string          `json:"error"`
+}
+
+func decodeResponse(resp *http.Response, result interface{}) error {
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "read response")
+	}
+
+	env := new(envelope)
+	if err = json.Unmarshal(data, env); err != nil {
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return &Error{StatusCode: resp.StatusCode, ErrCode: resp.StatusCode, Msg: http.StatusText(resp.StatusCode), Err: string(data)}
+		}
+		return errors.Wrapf(err, "decode response: %s", data)
+	}
+
+	failed := len(env.Error) > 0 ||
+		(env.Code != 0 && (env.Code < 200 || env.Code >= 300)) ||
+		resp.StatusCode < 200 || resp.StatusCode >= 300
+	if failed {
+		code := env.Code
+		if code == 0 {
+			code = resp.StatusCode
+		}
+		return &Error{StatusCode: resp.StatusCode, ErrCode: code, Msg: env.Message, Err: env.Error}
+	}
+
+	if result != nil && len(env.Result) > 0 {
+		if err = json.Unmarshal(env.Result, result); err != nil {
+			return errors.Wrapf(err, "decode result into %T", result)
+		}
+	}
+
+	return nil
+}
+
+// Error 服务端返回的错误，实现了 ginrpc.Err
+type Error struct {
+	StatusCode int
+	ErrCode    int
+	Msg        string
+	Err        string
+}
+
+var _ ginrpc.Err = (*Error)(nil)
+
+func (e *Error) Code() int {
+	return e.ErrCode
+}
+
+func (e *Error) Message() string {
+	return e.Msg
+}
+
+func (e *Error) Error() string {
+	if len(e.Err) > 0 {
+		return e.Err
+	}
+
+	if len(e.Msg) > 0 {
+		return e.Msg
+	}
+
+	return fmt.Sprintf("ginrpc: code %d", e.ErrCode)
+}
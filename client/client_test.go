@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alphaqiu/ginrpc"
+	"github.com/alphaqiu/ginrpc/mock/model"
+	"github.com/alphaqiu/ginrpc/mock/services/inventory"
+	"github.com/pkg/errors"
+)
+
+type Legacy struct{}
+
+func (l *Legacy) Routes() map[string]ginrpc.RouteSpec {
+	return map[string]ginrpc.RouteSpec{
+		"Purge": {Method: http.MethodDelete, Path: "/legacy/purge/:id"},
+	}
+}
+
+func (l *Legacy) Purge(ctx context.Context, path *model.ItemPath) (*model.InventoryModel, error) {
+	return &model.InventoryModel{Name: path.ID}, nil
+}
+
+// GetEcho 返回请求头 X-Lab, 用于检查客户端发送的 Header
+func (l *Legacy) GetEcho(ctx context.Context, header http.Header) (*model.InventoryModel, error) {
+	return &model.InventoryModel{Name: header.Get("X-Lab")}, nil
+}
+
+// newTestServer 使用真实的 ginrpc 服务, 保证客户端与服务端的路由和响应格式一致
+func newTestServer(t *testing.T) *httptest.Server {
+	httpServer := ginrpc.New(nil)
+	for _, svc := range []interface{}{&inventory.Inventory{}, &Legacy{}} {
+		if err := httpServer.Bind(svc); err != nil {
+			t.Fatalf("绑定服务失败: %v", err)
+		}
+	}
+	return httptest.NewServer(httpServer.Handler())
+}
+
+func TestService_Call(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	svc := NewClient(ts.URL).Service("Inventory", "v1")
+	ctx := context.Background()
+
+	result := new(model.InventoryModel)
+	if err := svc.Call(ctx, "GetRemove", result, model.InventoryQuery{Name: "jerry"}); err != nil {
+		t.Fatalf("GetRemove: %v", err)
+	}
+	if result.Name != "jerry" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	if err := svc.Call(ctx, "GetItem", result, model.ItemPath{ID: "a/b"}); err != nil || result.Name != "a/b" {
+		t.Fatalf("GetItem: %v, %+v", err, result)
+	}
+
+	if err := svc.Call(ctx, "DeleteItem", nil, &model.ItemPath{ID: "42"}); err != nil {
+		t.Fatalf("DeleteItem: %v", err)
+	}
+
+	if err := svc.Call(ctx, "OptionsEmpty", nil); err != nil {
+		t.Fatalf("OptionsEmpty: %v", err)
+	}
+
+	items := make([]*model.InventoryModel, 0)
+	err := svc.Call(ctx, "List", &items, &model.InventoryModel{Name: "alpha"}, model.InventoryQuery{Name: "beta"})
+	if err != nil || len(items) != 1 || items[0].Name != "alpha" {
+		t.Fatalf("List: %v, %+v", err, items)
+	}
+
+	err = svc.Call(ctx, "Header", nil, &model.InventoryModel{Name: "alpha"}, model.InventoryQuery{}, http.Header{"X-Lab": []string{"wow"}})
+	if err != nil {
+		t.Fatalf("Header: %v", err)
+	}
+
+	legacy := NewClient(ts.URL).Service("Legacy", "v0").WithRoutes(map[string]ginrpc.RouteSpec{
+		"Purge": {Method: http.MethodDelete, Path: "/legacy/purge/:id"},
+	})
+	if err := legacy.Call(ctx, "Purge", result, model.ItemPath{ID: "7"}); err != nil || result.Name != "7" {
+		t.Fatalf("Purge: %v, %+v", err, result)
+	}
+
+	if err := legacy.Call(ctx, "GetEcho", result, http.Header{"X-Lab": []string{"wow"}}); err != nil || result.Name != "wow" {
+		t.Fatalf("header not sent: %v, %+v", err, result)
+	}
+
+	err = svc.Call(ctx, "Add", nil, &model.InventoryModel{Name: "alpha"})
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("expect *Error, got %v", err)
+	}
+	if e.Code() != 400 || e.Message() != "testing mock error" || e.Error() != "mock error" {
+		t.Fatalf("unexpected error: %+v", e)
+	}
+
+	err = svc.Call(ctx, "Missing", nil)
+	if !errors.As(err, &e) || e.StatusCode != http.StatusNotFound {
+		t.Fatalf("expect 404 error, got %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// encodeForm 按照 gin form 绑定的规则把结构体编码为url参数
+func encodeForm(v interface{}, values url.Values) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("expect struct, got %s", rv.Type())
+	}
+
+	return encodeStruct(rv, values)
+}
+
+func encodeStruct(rv reflect.Value, values url.Values) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if len(field.PkgPath) > 0 && !field.Anonymous { // unexported
+			continue
+		}
+
+		tag := field.Tag.Get("form")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if len(name) == 0 {
+			name = field.Name
+		}
+
+		value := rv.Field(i)
+		for value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				break
+			}
+			value = value.Elem()
+		}
+
+		if value.Kind() == reflect.Ptr {
+			continue
+		}
+
+		if value.Kind() == reflect.Struct && value.Type() != timeType {
+			if err := encodeStruct(value, values); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+			for j := 0; j < value.Len(); j++ {
+				values.Add(name, formatValue(value.Index(j), field))
+			}
+			continue
+		}
+
+		values.Set(name, formatValue(value, field))
+	}
+
+	return nil
+}
+
+type pathParam struct {
+	name  string
+	value string
+}
+
+// encodePath 按照字段定义的顺序返回 uri 标签的值, 与服务端生成的 :param 路由一致
+func encodePath(v interface{}) ([]pathParam, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("nil path param %s", rv.Type())
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expect struct, got %s", rv.Type())
+	}
+
+	return pathSegments(rv)
+}
+
+func pathSegments(rv reflect.Value) ([]pathParam, error) {
+	segments := make([]pathParam, 0, rv.NumField())
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		value := rv.Field(i)
+		if value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return nil, fmt.Errorf("nil path field %s", field.Name)
+			}
+			value = value.Elem()
+		}
+
+		if field.Anonymous && value.Kind() == reflect.Struct {
+			embedded, err := pathSegments(value)
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, embedded...)
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("uri"), ",")[0]
+		if len(name) == 0 || name == "-" {
+			continue
+		}
+		segments = append(segments, pathParam{name: name, value: url.PathEscape(formatValue(value, field))})
+	}
+
+	return segments, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func formatValue(value reflect.Value, field reflect.StructField) string {
+	if value.Type() == timeType {
+		t := value.Interface().(time.Time)
+		format := field.Tag.Get("time_format")
+		switch format {
+		case "":
+			format = time.RFC3339
+		case "unix":
+			return fmt.Sprint(t.Unix())
+		case "unixnano":
+			return fmt.Sprint(t.UnixNano())
+		}
+		return t.Format(format)
+	}
+
+	return fmt.Sprint(value.Interface())
+}
@@ -0,0 +1,671 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/constant"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alphaqiu/ginrpc"
+	"github.com/pkg/errors"
+)
+
+const (
+	ginrpcPkg = "github.com/alphaqiu/ginrpc"
+	clientPkg = ginrpcPkg + "/client"
+)
+
+// reservedInterfaces 与 ginrpc 中框架使用的服务接口一致, 这些接口的方法不是服务接口.
+// 按方法名称和签名匹配, 服务所在的包不需要导入 ginrpc, 例如只实现了 Version() string
+var reservedInterfaces = []reflect.Type{
+	reflect.TypeOf((*ginrpc.ResourceVersion)(nil)).Elem(),
+	reflect.TypeOf((*ginrpc.ResourceRoutes)(nil)).Elem(),
+	reflect.TypeOf((*ginrpc.ResourceInterceptor)(nil)).Elem(),
+	reflect.TypeOf((*ginrpc.ResourceMiddleware)(nil)).Elem(),
+	reflect.TypeOf((*ginrpc.ActionMiddleware)(nil)).Elem(),
+	reflect.TypeOf((*ginrpc.ResourceIgnore)(nil)).Elem(),
+}
+
+type generator struct {
+	fset    *token.FileSet
+	files   []*ast.File
+	info    *types.Info
+	pkg     *types.Package
+	types   []string
+	pkgName string
+	version string
+	parser  *ginrpc.RouteParser
+	warn    func(format string, args ...interface{})
+
+	imports map[string]string // path -> name
+	names   map[string]string // name -> path
+}
+
+// load 解析并类型检查服务所在的包
+func load(pattern, wd string) (*generator, error) {
+	// 目录形式的参数需要通过 go list 得到真实的导入路径
+	cmd := exec.Command("go", "list", "-find", "-f", "{{.ImportPath}}\n{{.Dir}}", pattern)
+	cmd.Dir = wd
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "go list %s", pattern)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 2 {
+		return nil, errors.Errorf("expect exactly one package, got: %s", out)
+	}
+
+	bp, err := build.ImportDir(lines[1], 0)
+	if err != nil {
+		return nil, errors.Wrapf(err, "import %s", pattern)
+	}
+	bp.ImportPath = lines[0]
+
+	fset := token.NewFileSet()
+	files := make([]*ast.File, 0, len(bp.GoFiles))
+	for _, name := range bp.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(bp.Dir, name), nil, 0)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		files = append(files, f)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	info := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}
+	pkg, err := conf.Check(bp.ImportPath, fset, files, info)
+	if err != nil {
+		return nil, errors.Wrapf(err, "type check %s", bp.ImportPath)
+	}
+
+	return &generator{
+		fset:   fset,
+		files:  files,
+		info:   info,
+		pkg:    pkg,
+		parser: ginrpc.NewRouteParser(nil),
+		warn:   func(string, ...interface{}) {},
+	}, nil
+}
+
+type service struct {
+	Name     string
+	Version  string
+	Resource string
+	Actions  []*action
+}
+
+type action struct {
+	Name       string
+	HttpMethod string
+	Resource   string
+	Action     string
+	Path       string // 非空表示路由被 Routes() 覆盖
+	Params     []param
+	Result     string // 空表示只返回error
+}
+
+type param struct {
+	Name string
+	Type string
+}
+
+func (g *generator) generate() ([]byte, error) {
+	g.imports = make(map[string]string)
+	g.names = make(map[string]string)
+	for _, p := range []string{"context", "net/http", clientPkg} {
+		g.qualifier(types.NewPackage(p, filepath.Base(p)))
+	}
+
+	services, err := g.services()
+	if err != nil {
+		return nil, err
+	}
+
+	pkgName := g.pkgName
+	if len(pkgName) == 0 {
+		pkgName = g.pkg.Name() + "client"
+	}
+
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "// Code generated by ginrpc-gen. DO NOT EDIT.\n// source: %s\n\n", g.pkg.Path())
+	fmt.Fprintf(buf, "package %s\n\n", pkgName)
+
+	paths := make([]string, 0, len(g.imports))
+	for p := range g.imports {
+		paths = append(paths, p)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if isStdLib(paths[i]) != isStdLib(paths[j]) {
+			return isStdLib(paths[i])
+		}
+		return paths[i] < paths[j]
+	})
+	buf.WriteString("import (\n")
+	for i, p := range paths {
+		// 标准库与第三方包分组
+		if i > 0 && isStdLib(paths[i-1]) && !isStdLib(p) {
+			buf.WriteString("\n")
+		}
+
+		if filepath.Base(p) == g.imports[p] {
+			fmt.Fprintf(buf, "\t%q\n", p)
+		} else {
+			fmt.Fprintf(buf, "\t%s %q\n", g.imports[p], p)
+		}
+	}
+	buf.WriteString(")\n")
+
+	for _, svc := range services {
+		g.writeService(buf, svc)
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, errors.Wrapf(err, "format generated code:\n%s", buf.Bytes())
+	}
+	return src, nil
+}
+
+func (g *generator) writeService(buf *bytes.Buffer, svc *service) {
+	typeName := svc.Name + "Client"
+	fmt.Fprintf(buf, "\n// %s 调用服务 %s.%s, version: %s\n", typeName, g.pkg.Name(), svc.Name, svc.Version)
+	fmt.Fprintf(buf, "type %s struct {\n\tc *client.Client\n}\n\n", typeName)
+	fmt.Fprintf(buf, "func New%s(c *client.Client) *%s {\n\treturn &%s{c: c}\n}\n", typeName, typeName, typeName)
+
+	for _, a := range svc.Actions {
+		params := make([]string, 0, len(a.Params)+1)
+		args := make([]string, 0, len(a.Params))
+		params = append(params, "ctx context.Context")
+		for _, p := range a.Params {
+			params = append(params, p.Name+" "+p.Type)
+			args = append(args, p.Name)
+		}
+
+		invoke := fmt.Sprintf("cli.c.Invoke(ctx, %s, %q, %q, %q, %%s%s)",
+			httpMethodExpr(a.HttpMethod), svc.Version, a.Resource, a.Action, joinArgs(args))
+		route := fmt.Sprintf("%s/%s/%s", svc.Version, a.Resource, a.Action)
+		if len(a.Path) > 0 {
+			invoke = fmt.Sprintf("cli.c.Do(ctx, %s, %q, %%s%s)", httpMethodExpr(a.HttpMethod), a.Path, joinArgs(args))
+			route = a.Path
+		}
+
+		fmt.Fprintf(buf, "\n// %s %s %s\n", a.Name, a.HttpMethod, route)
+		if len(a.Result) == 0 {
+			fmt.Fprintf(buf, "func (cli *%s) %s(%s) error {\n", typeName, a.Name, strings.Join(params, ", "))
+			fmt.Fprintf(buf, "\treturn "+invoke+"\n}\n", "nil")
+			continue
+		}
+
+		fmt.Fprintf(buf, "func (cli *%s) %s(%s) (%s, error) {\n", typeName, a.Name, strings.Join(params, ", "), a.Result)
+		fmt.Fprintf(buf, "\tvar result %s\n", a.Result)
+		fmt.Fprintf(buf, "\terr := "+invoke+"\n", "&result")
+		buf.WriteString("\treturn result, err\n}\n")
+	}
+}
+
+func joinArgs(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return ", " + strings.Join(args, ", ")
+}
+
+func httpMethodExpr(method string) string {
+	switch method {
+	case "GET":
+		return "http.MethodGet"
+	case "POST":
+		return "http.MethodPost"
+	case "OPTIONS":
+		return "http.MethodOptions"
+	case "HEAD":
+		return "http.MethodHead"
+	case "PUT":
+		return "http.MethodPut"
+	case "PATCH":
+		return "http.MethodPatch"
+	case "DELETE":
+		return "http.MethodDelete"
+	}
+	return strconv.Quote(method)
+}
+
+func (g *generator) services() ([]*service, error) {
+	wanted := make(map[string]bool)
+	for _, name := range g.types {
+		if name = strings.TrimSpace(name); len(name) > 0 {
+			wanted[name] = true
+		}
+	}
+
+	scope := g.pkg.Scope()
+	services := make([]*service, 0)
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || !tn.Exported() {
+			continue
+		}
+
+		if len(wanted) > 0 && !wanted[name] {
+			continue
+		}
+		delete(wanted, name)
+
+		named, ok := tn.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+
+		if _, ok = named.Underlying().(*types.Struct); !ok {
+			continue
+		}
+
+		svc := g.service(named)
+		if len(svc.Actions) == 0 {
+			continue
+		}
+		services = append(services, svc)
+	}
+
+	for name := range wanted {
+		return nil, errors.Errorf("service %s not found in %s", name, g.pkg.Path())
+	}
+
+	if len(services) == 0 {
+		return nil, errors.Errorf("no service found in %s", g.pkg.Path())
+	}
+
+	return services, nil
+}
+
+func (g *generator) service(named *types.Named) *service {
+	svc := &service{Name: named.Obj().Name(), Version: g.resourceVersion(named)}
+	routes := g.resourceRoutes(named)
+	reserved := g.reservedMethods(named)
+	for name := range g.resourceIgnore(named) {
+		reserved[name] = true
+	}
+
+	mset := types.NewMethodSet(types.NewPointer(named))
+	for i := 0; i < mset.Len(); i++ {
+		fn, ok := mset.At(i).Obj().(*types.Func)
+		if !ok || !fn.Exported() || reserved[fn.Name()] {
+			continue
+		}
+
+		a, reason := g.action(fn.Type().(*types.Signature))
+		if a == nil {
+			g.warn("skip %s.%s: %s", svc.Name, fn.Name(), reason)
+			continue
+		}
+
+		a.Name = fn.Name()
+		a.HttpMethod, a.Resource, a.Action = g.parser.Parse(svc.Name, fn.Name())
+		if spec, ok := routes[fn.Name()]; ok {
+			a.HttpMethod, a.Path = spec.Resolve(a.HttpMethod, svc.Version, a.Resource, a.Action)
+		}
+		svc.Resource = a.Resource
+		svc.Actions = append(svc.Actions, a)
+	}
+
+	return svc
+}
+
+// action 与 ginServer.checkOutParams / initInParams 的规则保持一致
+func (g *generator) action(sig *types.Signature) (*action, string) {
+	results := sig.Results()
+	if results.Len() < 1 || results.Len() > 2 {
+		return nil, "expect 1 or 2 return values"
+	}
+
+	last := results.At(results.Len() - 1).Type()
+	if !types.IsInterface(last) && !types.Implements(last, errorType) {
+		return nil, "last return value must implement error"
+	}
+
+	if results.Len() == 2 && !isStructOrSlice(results.At(0).Type()) {
+		return nil, "first return value must be a struct or slice"
+	}
+
+	params := sig.Params()
+	if params.Len() < 1 || params.Len() > 5 {
+		return nil, "expect context and up to 4 params"
+	}
+
+	if !types.IsInterface(params.At(0).Type()) {
+		return nil, "first param must be context.Context"
+	}
+
+	for i := 1; i < params.Len(); i++ {
+		t := params.At(i).Type()
+		if !isHttpHeader(t) && !isStruct(t) {
+			return nil, fmt.Sprintf("unsupported param type %s", t)
+		}
+		// 客户端以 JSON 发送请求体, 不支持流式上传
+		if isUpload(t) {
+			return nil, "streaming upload is not supported by the client"
+		}
+	}
+
+	a := new(action)
+	if results.Len() == 2 {
+		a.Result = types.TypeString(results.At(0).Type(), g.qualifier)
+	}
+
+	typeNames := make([]string, params.Len())
+	for i := 1; i < params.Len(); i++ {
+		typeNames[i] = types.TypeString(params.At(i).Type(), g.qualifier)
+	}
+
+	// 参数名称不能遮蔽生成代码中使用到的包名和变量名
+	used := map[string]bool{"ctx": true, "cli": true, "result": true, "err": true}
+	for name := range g.names {
+		used[name] = true
+	}
+
+	for i := 1; i < params.Len(); i++ {
+		name := params.At(i).Name()
+		if len(name) == 0 || name == "_" || used[name] {
+			name = fmt.Sprintf("p%d", i)
+		}
+		used[name] = true
+		a.Params = append(a.Params, param{Name: name, Type: typeNames[i]})
+	}
+
+	return a, ""
+}
+
+func (g *generator) reservedMethods(named *types.Named) map[string]bool {
+	methods := make(map[string]string)
+	mset := types.NewMethodSet(types.NewPointer(named))
+	for i := 0; i < mset.Len(); i++ {
+		if fn, ok := mset.At(i).Obj().(*types.Func); ok {
+			methods[fn.Name()] = signatureString(fn.Type().(*types.Signature))
+		}
+	}
+
+	// 实现了接口的全部方法时, 这些方法都不是服务接口
+	reserved := make(map[string]bool)
+	for _, iface := range reservedInterfaces {
+		implemented := true
+		for i := 0; i < iface.NumMethod(); i++ {
+			m := iface.Method(i)
+			if sig, ok := methods[m.Name]; !ok || sig != normalizeSignature(m.Type.String()) {
+				implemented = false
+				break
+			}
+		}
+
+		if implemented {
+			for i := 0; i < iface.NumMethod(); i++ {
+				reserved[iface.Method(i).Name] = true
+			}
+		}
+	}
+	return reserved
+}
+
+// signatureString 与 reflect.Type.String() 的格式一致, 类型以包名限定, 不包含参数名称
+func signatureString(sig *types.Signature) string {
+	qualifier := func(p *types.Package) string { return p.Name() }
+	tuple := func(t *types.Tuple) []string {
+		ret := make([]string, t.Len())
+		for i := 0; i < t.Len(); i++ {
+			ret[i] = types.TypeString(t.At(i).Type(), qualifier)
+		}
+		return ret
+	}
+
+	str := "func(" + strings.Join(tuple(sig.Params()), ", ") + ")"
+	switch results := tuple(sig.Results()); len(results) {
+	case 0:
+	case 1:
+		str += " " + results[0]
+	default:
+		str += " (" + strings.Join(results, ", ") + ")"
+	}
+	return normalizeSignature(str)
+}
+
+var anyPattern = regexp.MustCompile(`\bany\b`)
+
+// normalizeSignature 统一 reflect 和 go/types 对空接口的不同写法
+func normalizeSignature(sig string) string {
+	sig = strings.ReplaceAll(sig, "interface {}", "interface{}")
+	return anyPattern.ReplaceAllString(sig, "interface{}")
+}
+
+// resourceVersion 只识别 func (T) Version() string { return "v1" } 这种常量写法
+func (g *generator) resourceVersion(named *types.Named) string {
+	if fd := g.methodDecl(named, "Version"); fd != nil {
+		if len(fd.Body.List) == 1 {
+			if ret, ok := fd.Body.List[0].(*ast.ReturnStmt); ok && len(ret.Results) == 1 {
+				if v, ok := g.constString(ret.Results[0]); ok {
+					return strings.ReplaceAll(strings.Trim(v, " "), " ", "_")
+				}
+			}
+		}
+
+		if len(g.version) == 0 {
+			g.warn("can not resolve %s.Version() statically, use -version", named.Obj().Name())
+		}
+	}
+
+	if len(g.version) > 0 {
+		return g.version
+	}
+	return "v0"
+}
+
+// resourceRoutes 解析 func (T) Routes() map[string]ginrpc.RouteSpec { return map[...]...{...} } 中的常量
+func (g *generator) resourceRoutes(named *types.Named) map[string]ginrpc.RouteSpec {
+	routes := make(map[string]ginrpc.RouteSpec)
+	fd := g.methodDecl(named, "Routes")
+	if fd == nil {
+		return routes
+	}
+
+	warn := func() map[string]ginrpc.RouteSpec {
+		g.warn("can not resolve %s.Routes() statically, generated methods use derived routes", named.Obj().Name())
+		return map[string]ginrpc.RouteSpec{}
+	}
+
+	if len(fd.Body.List) != 1 {
+		return warn()
+	}
+
+	ret, ok := fd.Body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return warn()
+	}
+
+	lit, ok := ret.Results[0].(*ast.CompositeLit)
+	if !ok {
+		return warn()
+	}
+
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			return warn()
+		}
+
+		name, ok := g.constString(kv.Key)
+		if !ok {
+			return warn()
+		}
+
+		value, ok := kv.Value.(*ast.CompositeLit)
+		if !ok {
+			return warn()
+		}
+
+		spec := ginrpc.RouteSpec{}
+		for _, field := range value.Elts {
+			fkv, ok := field.(*ast.KeyValueExpr)
+			if !ok {
+				return warn()
+			}
+
+			key, _ := fkv.Key.(*ast.Ident)
+			v, ok := g.constString(fkv.Value)
+			if key == nil || !ok {
+				return warn()
+			}
+
+			switch key.Name {
+			case "Method":
+				spec.Method = v
+			case "Path":
+				spec.Path = v
+			}
+		}
+		routes[name] = spec
+	}
+
+	return routes
+}
+
+// resourceIgnore 解析 func (T) Ignore() []string { return []string{...} } 中的常量,
+// 包含 Ignore 方法本身, 服务所在的包没有引用 ginrpc 时也能识别
+func (g *generator) resourceIgnore(named *types.Named) map[string]bool {
+	ignored := make(map[string]bool)
+	fd := g.methodDecl(named, "Ignore")
+	if fd == nil {
+		return ignored
+	}
+
+	var lit *ast.CompositeLit
+	if len(fd.Body.List) == 1 {
+		if ret, ok := fd.Body.List[0].(*ast.ReturnStmt); ok && len(ret.Results) == 1 {
+			lit, _ = ret.Results[0].(*ast.CompositeLit)
+		}
+	}
+
+	if lit == nil {
+		g.warn("can not resolve %s.Ignore() statically, ignored methods may be generated", named.Obj().Name())
+		return ignored
+	}
+
+	ignored["Ignore"] = true
+	for _, elt := range lit.Elts {
+		if name, ok := g.constString(elt); ok {
+			ignored[name] = true
+		}
+	}
+	return ignored
+}
+
+func (g *generator) constString(expr ast.Expr) (string, bool) {
+	tv, ok := g.info.Types[expr]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+func (g *generator) methodDecl(named *types.Named, name string) *ast.FuncDecl {
+	for _, f := range g.files {
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Recv == nil || fd.Name.Name != name || fd.Body == nil {
+				continue
+			}
+
+			if receiverName(fd.Recv.List[0].Type) == named.Obj().Name() {
+				return fd
+			}
+		}
+	}
+	return nil
+}
+
+func receiverName(expr ast.Expr) string {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+func (g *generator) qualifier(pkg *types.Package) string {
+	if name, ok := g.imports[pkg.Path()]; ok {
+		return name
+	}
+
+	name := pkg.Name()
+	for i := 2; ; i++ {
+		if _, ok := g.names[name]; !ok {
+			break
+		}
+		name = fmt.Sprintf("%s%d", pkg.Name(), i)
+	}
+
+	g.imports[pkg.Path()] = name
+	g.names[name] = pkg.Path()
+	return name
+}
+
+var errorType = types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+
+func isStruct(t types.Type) bool {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	_, ok := t.Underlying().(*types.Struct)
+	return ok
+}
+
+func isUpload(t types.Type) bool {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Name() == "Upload" && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == ginrpcPkg
+}
+
+func isStructOrSlice(t types.Type) bool {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+
+	switch t.Underlying().(type) {
+	case *types.Struct, *types.Slice:
+		return true
+	}
+	return false
+}
+
+func isHttpHeader(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "net/http" && obj.Name() == "Header"
+}
+
+func isStdLib(path string) bool {
+	return !strings.Contains(strings.Split(path, "/")[0], ".")
+}
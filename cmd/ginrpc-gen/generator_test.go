@@ -0,0 +1,92 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"strings"
+	"testing"
+)
+
+// typeCheck 生成的代码必须能够通过编译
+func typeCheck(t *testing.T, src []byte) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "client.go", src, 0)
+	if err != nil {
+		t.Fatalf("parse generated code: %v\n%s", err, src)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err = conf.Check(f.Name.Name, fset, []*ast.File{f}, nil); err != nil {
+		t.Errorf("generated code does not compile: %v\n%s", err, src)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	wd, _ := os.Getwd()
+	g, err := load("github.com/alphaqiu/ginrpc/mock/services/inventory", wd)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	skipped := make([]string, 0)
+	g.warn = func(format string, args ...interface{}) {
+		skipped = append(skipped, args[1].(string))
+	}
+
+	src, err := g.generate()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	code := string(src)
+	for _, want := range []string{
+		"package inventoryclient",
+		"func (cli *InventoryClient) GetRemove(ctx context.Context, query model.InventoryQuery) (*model.InventoryModel, error)",
+		`cli.c.Invoke(ctx, http.MethodGet, "v1", "inventory", "remove", &result, query)`,
+		`cli.c.Invoke(ctx, http.MethodOptions, "v1", "inventory", "empty", nil)`,
+		`cli.c.Invoke(ctx, http.MethodPost, "v1", "inventory", "header", nil, item, query, header)`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("missing %q in:\n%s", want, code)
+		}
+	}
+
+	// NotUsed 由 Ignore() 忽略, Version 实现了 ResourceVersion, 都不警告
+	if len(skipped) > 0 {
+		t.Errorf("unexpected skipped methods: %v", skipped)
+	}
+	typeCheck(t, src)
+}
+
+func TestGenerate_Routes(t *testing.T) {
+	wd, _ := os.Getwd()
+	g, err := load("./testdata/legacy", wd)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	src, err := g.generate()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	code := string(src)
+	if strings.Contains(code, "Before(") || strings.Contains(code, "After(") {
+		t.Errorf("interceptor methods should not be generated:\n%s", code)
+	}
+
+	for _, want := range []string{
+		`cli.c.Do(ctx, http.MethodDelete, "/legacy/purge/:id", nil, path)`,
+		`cli.c.Do(ctx, http.MethodPost, "/v2/legacy/rename-item/:id", nil, path)`,
+		`"github.com/alphaqiu/ginrpc/cmd/ginrpc-gen/testdata/legacy"`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("missing %q in:\n%s", want, code)
+		}
+	}
+	typeCheck(t, src)
+}
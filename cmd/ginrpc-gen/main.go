@@ -0,0 +1,96 @@
+// ginrpc-gen 根据服务结构体生成调用 ginrpc 服务的客户端代码
+//
+//	ginrpc-gen -pkg ./mock/services/inventory -out inventory_client.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/alphaqiu/ginrpc"
+)
+
+func main() {
+	var (
+		pkg      = flag.String("pkg", ".", "import path or directory of the package that defines the services")
+		names    = flag.String("type", "", "comma separated service struct names, default all services in the package")
+		out      = flag.String("out", "", "output file, default stdout")
+		pkgName  = flag.String("package", "", "package name of the generated code, default <pkg>client")
+		version  = flag.String("version", "", "resource version used when Version() can not be resolved statically")
+		prefixes = flag.String("prefixes", "", "extra method prefixes, same as Config.MethodPrefixes, e.g. List=GET,Create=POST")
+		naming   = flag.String("naming", "lower", "naming strategy of resource and action: lower, kebab, snake or camel")
+	)
+	flag.Parse()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		fatal(err)
+	}
+
+	g, err := load(*pkg, wd)
+	if err != nil {
+		fatal(err)
+	}
+
+	if len(*names) > 0 {
+		g.types = strings.Split(*names, ",")
+	}
+	g.pkgName = *pkgName
+	g.version = *version
+	if g.parser, err = routeParser(*prefixes, *naming); err != nil {
+		fatal(err)
+	}
+	g.warn = func(format string, args ...interface{}) {
+		fmt.Fprintf(os.Stderr, "ginrpc-gen: "+format+"\n", args...)
+	}
+
+	src, err := g.generate()
+	if err != nil {
+		fatal(err)
+	}
+
+	if len(*out) == 0 {
+		_, _ = os.Stdout.Write(src)
+		return
+	}
+
+	if err = ioutil.WriteFile(*out, src, 0644); err != nil {
+		fatal(err)
+	}
+}
+
+var namingStrategies = map[string]ginrpc.NamingStrategy{
+	"lower": ginrpc.LowerCaseNaming,
+	"kebab": ginrpc.KebabCaseNaming,
+	"snake": ginrpc.SnakeCaseNaming,
+	"camel": ginrpc.CamelCaseNaming,
+}
+
+func routeParser(prefixes, naming string) (*ginrpc.RouteParser, error) {
+	cnf := &ginrpc.Config{MethodPrefixes: make(map[string]string)}
+	if cnf.Naming = namingStrategies[naming]; cnf.Naming == nil {
+		return nil, fmt.Errorf("unknown naming strategy %q", naming)
+	}
+
+	for _, item := range strings.Split(prefixes, ",") {
+		if item = strings.TrimSpace(item); len(item) == 0 {
+			continue
+		}
+
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid prefix %q, expect Prefix=METHOD", item)
+		}
+		cnf.MethodPrefixes[kv[0]] = kv[1]
+	}
+
+	return ginrpc.NewRouteParser(cnf), nil
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "ginrpc-gen: %v\n", err)
+	os.Exit(1)
+}
@@ -0,0 +1,41 @@
+package legacy
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/alphaqiu/ginrpc"
+)
+
+type ItemPath struct {
+	ID string `uri:"id"`
+}
+
+type Legacy struct{}
+
+func (l *Legacy) Version() string {
+	return "v2"
+}
+
+func (l *Legacy) Routes() map[string]ginrpc.RouteSpec {
+	return map[string]ginrpc.RouteSpec{
+		"Purge":  {Method: http.MethodDelete, Path: "/legacy/purge/:id"},
+		"Rename": {Path: "rename-item/:id"},
+	}
+}
+
+func (l *Legacy) Purge(ctx context.Context, path ItemPath) error {
+	return nil
+}
+
+func (l *Legacy) Rename(ctx context.Context, path ItemPath) error {
+	return nil
+}
+
+func (l *Legacy) Before(ctx context.Context, action *ginrpc.ActionInfo) ginrpc.Err {
+	return nil
+}
+
+func (l *Legacy) After(ctx context.Context, action *ginrpc.ActionInfo, result interface{}, err ginrpc.Err) (interface{}, ginrpc.Err) {
+	return result, err
+}
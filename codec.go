@@ -0,0 +1,357 @@
+package ginrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ugorji/go/codec"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	MIMEJSON    = "application/json"
+	MIMEMsgPack = "application/msgpack"
+	MIMEXML     = "application/xml"
+	MIMEYAML    = "application/x-yaml"
+
+	codecKey = "ginrpc.codec"
+)
+
+// Codec 响应的编码, 根据请求的 Accept 选择, 通过 Config.Codecs 注册
+type Codec interface {
+	// ContentTypes 支持的 MIME 类型, 第一个作为响应的 Content-Type
+	ContentTypes() []string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	JSONCodec    Codec = jsonCodec{}
+	MsgPackCodec Codec = msgpackCodec{}
+	XMLCodec     Codec = xmlCodec{}
+	YAMLCodec    Codec = yamlCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentTypes() []string { return []string{MIMEJSON} }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) ContentTypes() []string { return []string{MIMEMsgPack, "application/x-msgpack"} }
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var (
+		buf []byte
+		mh  codec.MsgpackHandle
+	)
+	err := codec.NewEncoderBytes(&buf, &mh).Encode(v)
+	return buf, err
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	var mh codec.MsgpackHandle
+	return codec.NewDecoderBytes(data, &mh).Decode(v)
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) ContentTypes() []string { return []string{MIMEXML, "text/xml"} }
+
+// Marshal 字段名称和 omitempty 与 JSON 一致, 根元素为 response, 数组的每个元素以字段名称重复输出
+func (xmlCodec) Marshal(v interface{}) ([]byte, error) {
+	tree, err := jsonTree(v)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBufferString(xml.Header)
+	enc := xml.NewEncoder(buf)
+	if err = encodeXML(enc, "response", tree); err != nil {
+		return nil, err
+	}
+	if err = enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeXML(enc *xml.Encoder, name string, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case yaml.MapSlice:
+		start := xml.StartElement{Name: xml.Name{Local: name}}
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, item := range v {
+			if err := encodeXML(enc, item.Key.(string), item.Value); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+	case []interface{}:
+		for _, item := range v {
+			if err := encodeXML(enc, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return enc.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: name}})
+	}
+}
+
+func (xmlCodec) Unmarshal(data []byte, v interface{}) error { return xml.Unmarshal(data, v) }
+
+type yamlCodec struct{}
+
+func (yamlCodec) ContentTypes() []string {
+	return []string{MIMEYAML, "application/yaml", "text/yaml"}
+}
+
+// Marshal 字段名称和 omitempty 与 JSON 一致
+func (yamlCodec) Marshal(v interface{}) ([]byte, error) {
+	tree, err := jsonTree(v)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(tree)
+}
+
+func (yamlCodec) Unmarshal(data []byte, v interface{}) error { return yaml.Unmarshal(data, v) }
+
+// envelope defaultResponse 的响应结构, 固定 XML 和 YAML 中字段的顺序
+type envelope struct {
+	Code    interface{} `json:"code,omitempty"`
+	Result  interface{} `json:"result,omitempty"`
+	Message interface{} `json:"message,omitempty"`
+	Error   interface{} `json:"error,omitempty"`
+	Errors  interface{} `json:"errors,omitempty"`
+}
+
+func toEnvelopeStruct(h gin.H) (*envelope, bool) {
+	env := new(envelope)
+	for k, v := range h {
+		switch k {
+		case "code":
+			env.Code = v
+		case "result":
+			env.Result = v
+		case "message":
+			env.Message = v
+		case "error":
+			env.Error = v
+		case "errors":
+			env.Errors = v
+		default:
+			// 自定义 ResponseRenderer 的响应结构按照 JSON 的顺序输出
+			return nil, false
+		}
+	}
+	return env, true
+}
+
+// jsonTree 按照 JSON 编码的结果把 v 转换为有序的 yaml.MapSlice, []interface{} 和基本类型, XML 和 YAML 使用 json 标签
+func jsonTree(v interface{}) (interface{}, error) {
+	if h, ok := v.(gin.H); ok {
+		if env, ok := toEnvelopeStruct(h); ok {
+			v = env
+		}
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return decodeTree(dec)
+}
+
+func decodeTree(dec *json.Decoder) (interface{}, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		if t == '[' {
+			items := make([]interface{}, 0)
+			for dec.More() {
+				item, err := decodeTree(dec)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+			}
+			_, err = dec.Token()
+			return items, err
+		}
+
+		items := yaml.MapSlice{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeTree(dec)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, yaml.MapItem{Key: key, Value: value})
+		}
+		_, err = dec.Token()
+		return items, err
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return n, nil
+		}
+		return t.Float64()
+	default:
+		return t, nil
+	}
+}
+
+// codecRegistry 按 MIME 类型查找 Codec, 第一个注册的 Codec 为默认编码
+type codecRegistry struct {
+	codecs map[string]Codec
+	order  []string
+}
+
+func newCodecRegistry(extra []Codec) *codecRegistry {
+	r := &codecRegistry{codecs: make(map[string]Codec)}
+	for _, c := range append([]Codec{JSONCodec, MsgPackCodec, XMLCodec, YAMLCodec, ProtobufCodec}, extra...) {
+		for _, contentType := range c.ContentTypes() {
+			contentType = strings.ToLower(contentType)
+			if _, ok := r.codecs[contentType]; !ok {
+				r.order = append(r.order, contentType)
+			}
+			// 后注册的 Codec 覆盖内置的
+			r.codecs[contentType] = c
+		}
+	}
+	return r
+}
+
+func (r *codecRegistry) defaultCodec() Codec {
+	return r.codecs[MIMEJSON]
+}
+
+// negotiate 按照 Accept 的权重选择 Codec, 没有 Accept 时使用 JSON
+func (r *codecRegistry) negotiate(accept string) (Codec, bool) {
+	accepted, rejected := qualityValues(accept)
+	if len(accepted) == 0 && len(rejected) == 0 {
+		return r.defaultCodec(), true
+	}
+
+	// 拒绝一个 MIME 类型等同于拒绝对应的 Codec
+	for mime := range rejected {
+		if c, ok := r.codecs[mime]; ok {
+			for _, contentType := range c.ContentTypes() {
+				rejected[strings.ToLower(contentType)] = true
+			}
+		}
+	}
+
+	// 排在第一位的类型精确匹配时使用对应的 Codec
+	if len(accepted) > 0 {
+		if c, ok := r.codecs[strings.ToLower(accepted[0])]; ok {
+			return c, true
+		}
+	}
+
+	// 通配符或者排在后面的类型匹配时优先使用 JSON, 例如浏览器的 text/html,application/xml;q=0.9,*/*;q=0.8
+	if !rejected[MIMEJSON] {
+		for _, mime := range accepted {
+			switch strings.ToLower(mime) {
+			case MIMEJSON, "application/*", "*/*", "*":
+				return r.defaultCodec(), true
+			}
+		}
+	}
+
+	for _, mime := range accepted {
+		mime = strings.ToLower(mime)
+		if c, ok := r.codecs[mime]; ok {
+			return c, true
+		}
+
+		// */* 或者 application/* 按照注册的顺序匹配, JSON 优先, 跳过 q=0 的类型
+		if mime == "*/*" {
+			mime = "*"
+		}
+		if strings.HasSuffix(mime, "*") {
+			for _, contentType := range r.order {
+				if strings.HasPrefix(contentType, strings.TrimSuffix(mime, "*")) && !rejected[contentType] {
+					return r.codecs[contentType], true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// Respond 使用协商的 Codec 输出 v, 用于自定义的 ResponseRenderer
+func Respond(ctx *gin.Context, status int, v interface{}) {
+	c, ok := ctx.Value(codecKey).(Codec)
+	if !ok {
+		c = JSONCodec
+	}
+
+	data, err := c.Marshal(v)
+	if err != nil {
+		log.Errorf("编码响应失败: %T, %v", c, err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	// 二进制的编码不需要 charset
+	contentType := c.ContentTypes()[0]
+	if isTextCodec(c) {
+		contentType += "; charset=utf-8"
+	}
+	ctx.Data(status, contentType, data)
+}
+
+// isTextCodec JSON, XML, YAML 以及 text/* 为文本编码, 其他编码为二进制编码
+func isTextCodec(c Codec) bool {
+	switch contentType := c.ContentTypes()[0]; contentType {
+	case MIMEJSON, MIMEXML, MIMEYAML:
+		return true
+	default:
+		return strings.HasPrefix(contentType, "text/")
+	}
+}
+
+// notAcceptable 请求的 Accept 没有对应的 Codec
+type notAcceptable struct {
+	accept string
+}
+
+func (e *notAcceptable) Code() int {
+	return http.StatusNotAcceptable
+}
+
+func (e *notAcceptable) Message() string {
+	return "not acceptable"
+}
+
+func (e *notAcceptable) Error() string {
+	return "unsupported Accept: " + e.accept
+}
+
+func (e *notAcceptable) HTTPStatus() int {
+	return http.StatusNotAcceptable
+}
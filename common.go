@@ -1,12 +1,20 @@
 package ginrpc
 
 import (
+	"context"
+	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
 	"net/http"
+	"reflect"
 )
 
 var (
-	invalidInstanceErr = errors.New("无效的服务实例，服务实例必须是结构体指针")
+	invalidInstanceErr   = errors.New("无效的服务实例，服务实例必须是结构体指针")
+	invalidRouteErr      = errors.New("无效的路由配置")
+	invalidMiddlewareErr = errors.New("无效的中间件配置")
+	routeConflictErr     = errors.New("路由冲突")
+	rejectedMethodsErr   = errors.New("存在无效的服务方法")
+	notBoundErr          = errors.New("服务没有绑定")
 )
 
 type Err interface {
@@ -15,6 +23,11 @@ type Err interface {
 	Error() string
 }
 
+// HTTPStatusErr Err 实现该接口后, 响应使用 HTTPStatus() 返回的 HTTP 状态码
+type HTTPStatusErr interface {
+	HTTPStatus() int
+}
+
 type internalError struct {
 	error
 }
@@ -31,11 +44,68 @@ func (e *internalError) Error() string {
 	return e.error.Error()
 }
 
+// BindError 绑定入參失败, Fields 为校验失败的字段
+type BindError struct {
+	Msg    string
+	Err    error
+	Fields []ValidationError
+}
+
+func (e *BindError) Code() int {
+	return http.StatusBadRequest
+}
+
+func (e *BindError) Message() string {
+	return e.Msg
+}
+
+func (e *BindError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
 type ResourceVersion interface {
 	Version() string
 }
 
+// ResourceRoutes 服务可以为指定的Go方法覆盖HTTP Method和路径, key为方法名称
+type ResourceRoutes interface {
+	Routes() map[string]RouteSpec
+}
+
+// ResourceInterceptor 服务实现该接口后, 每个action调用前后都会执行.
+// Before 在绑定参数之前执行, 返回非nil的Err时拒绝本次调用并把该错误返回给客户端;
+// After 在action返回之后执行, 可以替换返回的结果和错误
 type ResourceInterceptor interface {
-	Before()
-	After()
+	Before(ctx context.Context, action *ActionInfo) Err
+	After(ctx context.Context, action *ActionInfo, result interface{}, err Err) (interface{}, Err)
+}
+
+// ResourceMiddleware 服务实现该接口后, 返回的中间件只作用于该服务的所有action
+type ResourceMiddleware interface {
+	Middlewares() []gin.HandlerFunc
+}
+
+// ActionMiddleware 服务实现该接口后, 返回的中间件只作用于对应的action, key为Go方法名称
+type ActionMiddleware interface {
+	ActionMiddlewares() map[string][]gin.HandlerFunc
+}
+
+// ResourceIgnore 服务实现该接口后, 返回的Go方法不会被绑定为action, 用于导出的辅助方法.
+// Config.StrictBind 开启时, 签名无效且没有被忽略的导出方法会使 Bind 返回错误
+type ResourceIgnore interface {
+	Ignore() []string
+}
+
+// reservedInterfaces 框架使用的服务接口, 这些接口的方法不会被绑定为action
+var reservedInterfaces = []reflect.Type{
+	reflect.TypeOf((*ResourceVersion)(nil)).Elem(),
+	reflect.TypeOf((*ResourceRoutes)(nil)).Elem(),
+	reflect.TypeOf((*ResourceInterceptor)(nil)).Elem(),
+	reflect.TypeOf((*ResourceMiddleware)(nil)).Elem(),
+	reflect.TypeOf((*ActionMiddleware)(nil)).Elem(),
+	reflect.TypeOf((*ResourceIgnore)(nil)).Elem(),
 }
@@ -2,6 +2,8 @@ package ginrpc
 
 import (
 	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 const (
@@ -9,6 +11,7 @@ const (
 	DefaultWriteTimeout    = time.Minute
 	DefaultIdleTimeout     = 2 * time.Minute
 	DefaultShutdownTimeout = 10 * time.Second
+	DefaultSSEKeepAlive    = 15 * time.Second
 )
 
 func defaultConfig() *Config {
@@ -21,6 +24,7 @@ func defaultConfig() *Config {
 		IdleTimeout:     DefaultIdleTimeout,
 		ShutdownTimeout: DefaultShutdownTimeout,
 		UrlPrefix:       "/api",
+		SSEKeepAlive:    DefaultSSEKeepAlive,
 	}
 }
 
@@ -34,6 +38,38 @@ type Config struct {
 	IdleTimeout     time.Duration `mapstructure:"idle_timeout"`
 	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
 	UrlPrefix       string        `mapstructure:"url_prefix"`
+	OpenAPI         *OpenAPIInfo  `mapstructure:"openapi"`
+	// MethodPrefixes 方法名称前缀到 HTTP Method 的映射, 与 DefaultMethodPrefixes 合并, 例如 List -> GET, 转换为大写后不是有效的 HTTP Method 时忽略并输出警告
+	MethodPrefixes map[string]string `mapstructure:"method_prefixes"`
+	// Naming 资源和action名称的命名方式, 默认为 LowerCaseNaming
+	Naming NamingStrategy `mapstructure:"-"`
+	// StrictBind 导出方法的签名无效时 Bind 返回错误, 而不是跳过该方法
+	StrictBind bool `mapstructure:"strict_bind"`
+	// DefaultLocale 请求没有指定支持的语言时使用的语言, 支持 en 和 zh, 默认为 en
+	DefaultLocale string `mapstructure:"default_locale"`
+	// Messages 按语言注册的翻译, key 为 Err.Message() 的原文, 例如 zh -> {"item not found": "商品不存在"}
+	Messages map[string]map[string]string `mapstructure:"messages"`
+	// HTTPStatus 开启后 Err.Code() 在 400-599 之间时作为响应的 HTTP 状态码, 默认总是返回200
+	HTTPStatus bool `mapstructure:"http_status"`
+	// StatusCodes Err.Code() 到 HTTP 状态码的映射, 例如 10001 -> 409, 优先于 HTTPStatus
+	StatusCodes map[int]int `mapstructure:"status_codes"`
+	// Codecs 在内置的 JSON, MsgPack, XML, YAML, Protobuf 之外注册的响应编码, 相同的 MIME 类型覆盖内置的 Codec
+	Codecs []Codec `mapstructure:"-"`
+	// SSEKeepAlive 流式action发送 keepalive 注释的间隔, 0 表示不发送
+	SSEKeepAlive time.Duration `mapstructure:"sse_keepalive"`
+	// MaxUploadSize 请求体的最大字节数, 超过时返回413, 0 表示不限制; 可以通过 WithUploadLimit 为单个action设置
+	MaxUploadSize int64 `mapstructure:"max_upload_size"`
+	// WebSocket 升级 WebSocket 连接的参数, 例如 CheckOrigin, 默认只允许同源的请求
+	WebSocket *websocket.Upgrader `mapstructure:"-"`
+	// Renderer 输出action的结果和错误, 默认为 DefaultRenderer
+	Renderer ResponseRenderer `mapstructure:"-"`
+}
+
+// OpenAPIInfo 对应 OpenAPI 文档中的 info 字段
+type OpenAPIInfo struct {
+	Title       string `mapstructure:"title" json:"title"`
+	Description string `mapstructure:"description" json:"description,omitempty"`
+	Version     string `mapstructure:"version" json:"version"`
 }
 
 type HttpTls struct {
@@ -0,0 +1,149 @@
+package ginrpc
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ParamInPath   = "path"
+	ParamInQuery  = "query"
+	ParamInBody   = "body"
+	ParamInHeader = "header"
+	ParamInStream = "stream"
+)
+
+// ActionInfo 描述一个绑定的服务接口, 由 /exports 返回
+type ActionInfo struct {
+	Method   string      `json:"method"`
+	Path     string      `json:"path"`
+	Version  string      `json:"version"`
+	Resource string      `json:"resource"`
+	Action   string      `json:"action"`
+	GoMethod string      `json:"goMethod"`
+	Params   []ParamInfo `json:"params"`
+	Result   string      `json:"result,omitempty"`
+}
+
+// ParamInfo 服务接口的入參, In 为 path, query, body, header 或 stream(WebSocket 的消息)
+type ParamInfo struct {
+	In   string `json:"in"`
+	Type string `json:"type"`
+}
+
+func newActionInfo(relativePath string, p *actionInOutParams) *ActionInfo {
+	info := &ActionInfo{
+		Method:   p.ReqMethod,
+		Path:     "/" + strings.TrimLeft(relativePath, "/"),
+		Version:  p.Version,
+		Resource: p.ResourceName,
+		Action:   p.ActionName,
+		GoMethod: p.MethodName,
+		Params:   make([]ParamInfo, 0, 3),
+	}
+
+	if p.HasPath {
+		info.Params = append(info.Params, ParamInfo{In: ParamInPath, Type: typeName(p.Path, p.PathKind)})
+	}
+
+	if p.HasQuery {
+		info.Params = append(info.Params, ParamInfo{In: ParamInQuery, Type: typeName(p.Query, p.QueryKind)})
+	}
+
+	if p.HasBody {
+		info.Params = append(info.Params, ParamInfo{In: ParamInBody, Type: typeName(p.Body, p.BodyKind)})
+	}
+
+	if p.HasHeader {
+		info.Params = append(info.Params, ParamInfo{In: ParamInHeader, Type: "http.Header"})
+	}
+
+	if p.Upload != nil {
+		info.Params = append(info.Params, ParamInfo{In: ParamInBody, Type: p.Upload.String()})
+	}
+
+	if p.Socket != nil {
+		info.Params = append(info.Params, ParamInfo{In: ParamInStream, Type: p.Socket.String()})
+	}
+
+	if p.Result != nil {
+		info.Result = p.Result.String()
+	}
+
+	return info
+}
+
+func typeName(t reflect.Type, kind reflect.Kind) string {
+	if kind == reflect.Ptr {
+		return "*" + t.String()
+	}
+	return t.String()
+}
+
+// exports 返回所有绑定的服务接口, 通过 format 参数选择输出格式:
+// json(默认) 结构化的接口描述, paths 仅包含路径, text 纯文本, openapi OpenAPI 3 文档
+func (g *ginServer) exports(c *gin.Context) {
+	services := g.snapshot()
+	switch format := c.Query("format"); format {
+	case "", "json":
+		apis := make([]*ActionInfo, len(services))
+		for idx, item := range services {
+			apis[idx] = item.Params.Info
+		}
+		c.JSON(http.StatusOK, gin.H{"apis": apis})
+	case "paths":
+		apis := make([]string, len(services))
+		for idx, item := range services {
+			apis[idx] = item.RelativePath
+		}
+		c.JSON(http.StatusOK, gin.H{"apis": apis})
+	case "text":
+		buf := new(strings.Builder)
+		for _, item := range services {
+			info := item.Params.Info
+			params := make([]string, len(info.Params))
+			for idx, param := range info.Params {
+				params[idx] = param.In + ":" + param.Type
+			}
+			_, _ = fmt.Fprintf(buf, "%-8s %-40s %s(%s) %s\n",
+				info.Method, info.Path, info.GoMethod, strings.Join(params, ", "), info.Result)
+		}
+		c.String(http.StatusOK, buf.String())
+	case "openapi":
+		c.JSON(http.StatusOK, g.openAPI())
+	default:
+		// 与服务接口的错误一样经过 Config.Renderer, 按照 Accept 编码
+		c.Set(translatorKey, g.i18n.find(c))
+		codec, ok := g.codecs.negotiate(c.GetHeader("Accept"))
+		if !ok {
+			codec = g.codecs.defaultCodec()
+		}
+		c.Set(codecKey, codec)
+		g.render(c, http.StatusBadRequest, nil, &unsupportedFormat{format: format})
+	}
+}
+
+// unsupportedFormat exports 的 format 参数无效
+type unsupportedFormat struct {
+	format string
+}
+
+func (e *unsupportedFormat) Code() int {
+	return http.StatusBadRequest
+}
+
+func (e *unsupportedFormat) Message() string {
+	return "unsupported format"
+}
+
+func (e *unsupportedFormat) Error() string {
+	return "unsupported format: " + e.format
+}
+
+func (e *unsupportedFormat) HTTPStatus() int {
+	return http.StatusBadRequest
+}
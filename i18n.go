@@ -0,0 +1,123 @@
+package ginrpc
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	entrans "github.com/go-playground/validator/v10/translations/en"
+	zhtrans "github.com/go-playground/validator/v10/translations/zh"
+)
+
+const (
+	DefaultLocale = "en"
+	// HeaderLanguage 优先于 Accept-Language 的语言请求头, 与 cors 中间件允许的请求头一致
+	HeaderLanguage = "X-Language"
+
+	translatorKey = "ginrpc.translator"
+)
+
+// supportedLocales 支持的语言以及对应的校验错误翻译
+var supportedLocales = map[string]struct {
+	locale     func() locales.Translator
+	validation func(v *validator.Validate, trans ut.Translator) error
+}{
+	"en": {locale: en.New, validation: entrans.RegisterDefaultTranslations},
+	"zh": {locale: zh.New, validation: zhtrans.RegisterDefaultTranslations},
+}
+
+// builtinMessages 框架返回的 message, key 为英文原文
+var builtinMessages = map[string]map[string]string{
+	"zh": {
+		"failed to bind params in path":  "路径参数绑定失败",
+		"failed to bind params in query": "查询参数绑定失败",
+		"failed to bind params in body":  "请求体绑定失败",
+		"unknown server error":           "未知的服务器错误",
+	},
+}
+
+// translator 根据请求的语言翻译 Err.Message() 和校验错误
+type translator struct {
+	uni *ut.UniversalTranslator
+}
+
+func newTranslator(cnf *Config) *translator {
+	fallback := cnf.DefaultLocale
+	if _, ok := supportedLocales[fallback]; !ok {
+		if len(fallback) > 0 {
+			log.Warnf("不支持的默认语言 %s, 使用 %s", fallback, DefaultLocale)
+		}
+		fallback = DefaultLocale
+	}
+
+	// 默认语言作为 fallback, 其他语言按名称排序保证注册的顺序稳定
+	names := make([]string, 0, len(supportedLocales))
+	for name := range supportedLocales {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	translators := make([]locales.Translator, 0, len(names))
+	for _, name := range names {
+		translators = append(translators, supportedLocales[name].locale())
+	}
+	t := &translator{uni: ut.New(supportedLocales[fallback].locale(), translators...)}
+
+	// 校验错误的翻译由 validatorEngine 在进程内注册一次, 这里只注册 message 的翻译
+	for _, name := range names {
+		trans, _ := t.uni.GetTranslator(name)
+		for _, catalog := range []map[string]string{builtinMessages[name], cnf.Messages[name]} {
+			for key, text := range catalog {
+				if err := trans.Add(key, text, true); err != nil {
+					log.Warnf("无效的翻译 %s: %s -> %s: %v", name, key, text, err)
+				}
+			}
+		}
+	}
+
+	for name := range cnf.Messages {
+		if _, ok := supportedLocales[name]; !ok {
+			log.Warnf("不支持的语言 %s, 该语言的翻译不会生效", name)
+		}
+	}
+	return t
+}
+
+// find 按照 X-Language, Accept-Language 的顺序选择语言, 都不支持时使用默认语言
+func (t *translator) find(ctx *gin.Context) ut.Translator {
+	candidates := make([]string, 0, 4)
+	accepted, _ := qualityValues(ctx.GetHeader("Accept-Language"))
+	for _, lang := range accepted {
+		if lang != "*" {
+			candidates = append(candidates, lang)
+		}
+	}
+	if lang := strings.TrimSpace(ctx.GetHeader(HeaderLanguage)); len(lang) > 0 {
+		candidates = append([]string{lang}, candidates...)
+	}
+
+	locales := make([]string, 0, len(candidates)*2)
+	for _, lang := range candidates {
+		// zh-CN 依次尝试 zh_cn 和 zh
+		lang = strings.ReplaceAll(lang, "-", "_")
+		locales = append(locales, lang, strings.Split(lang, "_")[0])
+	}
+
+	trans, _ := t.uni.FindTranslator(locales...)
+	return trans
+}
+
+// Translate 按照请求的语言翻译 key, 用于 ResponseRenderer 翻译 Err.Message(), 没有对应的翻译时返回 key 本身
+func Translate(ctx *gin.Context, key string, params ...string) string {
+	if trans, ok := ctx.Value(translatorKey).(ut.Translator); ok {
+		if text, err := trans.T(key, params...); err == nil {
+			return text
+		}
+	}
+	return key
+}
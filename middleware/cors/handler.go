@@ -9,7 +9,7 @@ import (
 
 const (
 	DefaultOrigin  = "*"
-	DefaultMethods = "GET, POST, PUT, DELETE, OPTIONS"
+	DefaultMethods = "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS"
 	DefaultHeaders = "DNT,X-Mx-ReqToken,Keep-Alive,User-Agent,X-Requested-With,If-Modified-Since,Cache-Control,Content-Type,Authorization,X-Language,X-Api-Key,X-Api-Secret,Content-Disposition"
 )
 
@@ -7,3 +7,7 @@ type InventoryModel struct {
 type InventoryQuery struct {
 	Name string `form:"name"`
 }
+
+type ItemPath struct {
+	ID string `uri:"id" binding:"required"`
+}
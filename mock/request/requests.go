@@ -11,6 +11,8 @@ func NewMockRequest(method, url string, body io.Reader, header http.Header) *htt
 	req.Header.Set("Content-Type", "application/json; charset=utf8")
 	if header != nil {
 		for k, v := range header {
+			// 覆盖默认的 Content-Type
+			req.Header.Del(k)
 			for _, item := range v {
 				req.Header.Add(k, item)
 			}
@@ -50,6 +50,11 @@ func (api *Inventory) Version() string {
 	return "v1"
 }
 
+// Ignore NotUsed 是辅助方法, 不作为服务接口绑定
+func (api *Inventory) Ignore() []string {
+	return []string{"NotUsed"}
+}
+
 func (api *Inventory) NotUsed(a string) error {
 	return nil
 }
@@ -73,6 +78,16 @@ func (api *Inventory) GetRemove(ctx context.Context, query model.InventoryQuery)
 	return &model.InventoryModel{Name: query.Name}, Success()
 }
 
+func (api *Inventory) GetItem(ctx context.Context, path model.ItemPath) (*model.InventoryModel, error) {
+	log.Debugf("Invoke Inventory Item Method[GET] -> ID: %s", path.ID)
+	return &model.InventoryModel{Name: path.ID}, Success()
+}
+
+func (api *Inventory) DeleteItem(ctx context.Context, path *model.ItemPath) error {
+	log.Debugf("Invoke Inventory Item Method[DELETE] -> ID: %s", path.ID)
+	return Success()
+}
+
 func (api *Inventory) GetData(ctx context.Context, query model.InventoryQuery) (model.InventoryModel, error) {
 	log.Debugf("Invoke Inventory Data Method[GET] -> Name: %s", query.Name)
 	return model.InventoryModel{Name: query.Name}, Success()
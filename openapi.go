@@ -0,0 +1,371 @@
+package ginrpc
+
+import (
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const openAPIVersion = "3.0.3"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// openAPI 由绑定的服务生成 OpenAPI 3 文档
+func (g *ginServer) openAPI() *openAPIDoc {
+	info := g.cnf.OpenAPI
+	if info == nil {
+		info = &OpenAPIInfo{Title: "ginrpc", Version: "1.0.0"}
+	}
+
+	b := newSchemaBuilder()
+	doc := &openAPIDoc{
+		OpenAPI: openAPIVersion,
+		Info:    info,
+		Paths:   make(map[string]openAPIPath),
+	}
+
+	for _, item := range g.snapshot() {
+		p := "/" + strings.TrimLeft(item.RelativePath, "/")
+		for _, name := range item.Params.PathParams {
+			p = strings.Replace(p, "/:"+name, "/{"+name+"}", 1)
+		}
+		if _, ok := doc.Paths[p]; !ok {
+			doc.Paths[p] = make(openAPIPath)
+		}
+		doc.Paths[p][strings.ToLower(item.Method)] = b.operation(item.Params)
+	}
+
+	doc.Components.Schemas = b.schemas
+	return doc
+}
+
+type openAPIDoc struct {
+	OpenAPI    string                 `json:"openapi"`
+	Info       *OpenAPIInfo           `json:"info"`
+	Paths      map[string]openAPIPath `json:"paths"`
+	Components openAPIComponents      `json:"components"`
+}
+
+type openAPIPath map[string]*openAPIOperation
+
+type openAPIOperation struct {
+	OperationId string                      `json:"operationId"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []*openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required,omitempty"`
+	Schema   *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                         `json:"required"`
+	Content  map[string]*openAPIMediaType `json:"content"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]*openAPISchema `json:"schemas"`
+}
+
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Nullable             bool                      `json:"nullable,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	AllOf                []*openAPISchema          `json:"allOf,omitempty"`
+}
+
+type schemaBuilder struct {
+	schemas map[string]*openAPISchema
+	names   map[reflect.Type]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	b := &schemaBuilder{
+		schemas: make(map[string]*openAPISchema),
+		names:   make(map[reflect.Type]string),
+	}
+
+	// 与 defaultResponse 的响应结构一致
+	b.schemas["Envelope"] = &openAPISchema{
+		Type: "object",
+		Properties: map[string]*openAPISchema{
+			"code":    {Type: "integer", Format: "int32"},
+			"message": {Type: "string"},
+			"error":   {Type: "string"},
+		},
+		Required: []string{"code"},
+	}
+	b.schemas["Envelope"].Properties["errors"] = b.schema(reflect.TypeOf([]ValidationError{}))
+	return b
+}
+
+func (b *schemaBuilder) operation(p *actionInOutParams) *openAPIOperation {
+	op := &openAPIOperation{
+		OperationId: strings.Join([]string{p.ResourceName, p.Version, p.MethodName}, "."),
+		Tags:        []string{p.ResourceName},
+		Responses:   make(map[string]*openAPIResponse),
+	}
+
+	if p.HasPath {
+		for _, param := range b.parameters(p.Path, "path", "uri") {
+			// 没有 uri 标签的字段不在路由中
+			for _, name := range p.PathParams {
+				if param.Name == name {
+					param.Required = true
+					op.Parameters = append(op.Parameters, param)
+				}
+			}
+		}
+	}
+
+	if p.HasQuery {
+		op.Parameters = append(op.Parameters, b.parameters(p.Query, "query", "form")...)
+	}
+
+	if p.HasBody {
+		// GET 请求的body参数以Form的方式绑定
+		if p.ReqMethod == http.MethodGet {
+			op.Parameters = append(op.Parameters, b.parameters(p.Body, "query", "form")...)
+		} else {
+			// 包含文件字段的请求体以 multipart/form-data 上传
+			mediaType := MIMEJSON
+			if hasFileField(p.Body) {
+				mediaType = MIMEMultipartForm
+			}
+			op.RequestBody = &openAPIRequestBody{
+				Required: true,
+				Content: map[string]*openAPIMediaType{
+					mediaType: {Schema: b.schema(p.Body)},
+				},
+			}
+		}
+	}
+
+	if p.Upload != nil {
+		op.RequestBody = &openAPIRequestBody{
+			Required: true,
+			Content: map[string]*openAPIMediaType{
+				"application/octet-stream": {Schema: &openAPISchema{Type: "string", Format: "binary"}},
+			},
+		}
+	}
+
+	if p.Socket != nil {
+		op.Responses["101"] = &openAPIResponse{Description: "Switching Protocols"}
+		return op
+	}
+
+	if p.Stream {
+		// 流式action的每个事件为一个元素
+		op.Responses["200"] = &openAPIResponse{
+			Description: "OK",
+			Content: map[string]*openAPIMediaType{
+				MIMEEventStream: {Schema: b.schema(p.Result.Elem())},
+			},
+		}
+		return op
+	}
+
+	envelope := &openAPISchema{Ref: "#/components/schemas/Envelope"}
+	if p.Result != nil {
+		envelope = &openAPISchema{AllOf: []*openAPISchema{envelope, {
+			Type:       "object",
+			Properties: map[string]*openAPISchema{"result": b.schema(p.Result)},
+		}}}
+	}
+
+	op.Responses["200"] = &openAPIResponse{
+		Description: "OK",
+		Content: map[string]*openAPIMediaType{
+			"application/json": {Schema: envelope},
+		},
+	}
+	return op
+}
+
+// parameters 按照 gin form 绑定的规则展开结构体的字段
+func (b *schemaBuilder) parameters(t reflect.Type, in, tag string) []*openAPIParameter {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	params := make([]*openAPIParameter, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) > 0 && !field.Anonymous {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get(tag), ",")[0]
+		if name == "-" {
+			continue
+		}
+
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if ft.Kind() == reflect.Struct && ft != timeType {
+			params = append(params, b.parameters(ft, in, tag)...)
+			continue
+		}
+
+		if len(name) == 0 {
+			name = field.Name
+		}
+
+		params = append(params, &openAPIParameter{
+			Name:     name,
+			In:       in,
+			Required: isRequired(field),
+			Schema:   b.schema(field.Type),
+		})
+	}
+
+	return params
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) *openAPISchema {
+	if t == fileHeaderType {
+		return &openAPISchema{Type: "string", Format: "binary"}
+	}
+
+	if t.Kind() == reflect.Ptr {
+		s := b.schema(t.Elem())
+		if len(s.Ref) > 0 {
+			return s
+		}
+		s.Nullable = true
+		return s
+	}
+
+	if t == timeType {
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &openAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &openAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &openAPISchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &openAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &openAPISchema{Type: "string", Format: "byte"}
+		}
+		return &openAPISchema{Type: "array", Items: b.schema(t.Elem())}
+	case reflect.Map:
+		return &openAPISchema{Type: "object", AdditionalProperties: b.schema(t.Elem())}
+	case reflect.Struct:
+		if len(t.Name()) == 0 {
+			return b.object(t)
+		}
+		return &openAPISchema{Ref: "#/components/schemas/" + b.register(t)}
+	}
+
+	// interface 等无法描述的类型
+	return &openAPISchema{}
+}
+
+func (b *schemaBuilder) register(t reflect.Type) string {
+	if name, ok := b.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, ok := b.schemas[name]; ok {
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+
+	// 先占位，避免递归类型无限展开
+	b.names[t] = name
+	b.schemas[name] = &openAPISchema{}
+	*b.schemas[name] = *b.object(t)
+	return name
+}
+
+func (b *schemaBuilder) object(t reflect.Type) *openAPISchema {
+	s := &openAPISchema{Type: "object", Properties: make(map[string]*openAPISchema)}
+	b.fields(t, s)
+	return s
+}
+
+// fields 按照 encoding/json 的规则收集字段
+func (b *schemaBuilder) fields(t reflect.Type, s *openAPISchema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) > 0 && !field.Anonymous {
+			continue
+		}
+
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		name := tag[0]
+		if name == "-" && len(tag) == 1 {
+			continue
+		}
+
+		ft := field.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if field.Anonymous && len(name) == 0 && ft.Kind() == reflect.Struct {
+			b.fields(ft, s)
+			continue
+		}
+
+		if len(name) == 0 {
+			name = field.Name
+		}
+
+		fs := b.schema(field.Type)
+		for _, opt := range tag[1:] {
+			if opt == "string" {
+				fs = &openAPISchema{Type: "string"}
+			}
+		}
+
+		s.Properties[name] = fs
+		if isRequired(field) {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+func isRequired(field reflect.StructField) bool {
+	for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
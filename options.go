@@ -0,0 +1,78 @@
+package ginrpc
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// BindOption 绑定服务时的选项, 同一个结构体可以通过不同的选项绑定多次
+type BindOption func(o *bindOptions)
+
+type bindOptions struct {
+	name              string
+	version           string
+	prefix            *string
+	filter            func(method string) bool
+	middlewares       []gin.HandlerFunc
+	actionMiddlewares map[string][]gin.HandlerFunc
+	uploadLimits      map[string]int64
+}
+
+func newBindOptions(opts []BindOption) *bindOptions {
+	o := &bindOptions{
+		actionMiddlewares: make(map[string][]gin.HandlerFunc),
+		uploadLimits:      make(map[string]int64),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithName 资源名称, 默认为结构体名称经过 Config.Naming 转换后的名称, 设置后按原样使用
+func WithName(name string) BindOption {
+	return func(o *bindOptions) {
+		o.name = name
+	}
+}
+
+// WithVersion 资源版本, 优先于 ResourceVersion
+func WithVersion(version string) BindOption {
+	return func(o *bindOptions) {
+		o.version = version
+	}
+}
+
+// WithPrefix 替换该服务路由的 Config.UrlPrefix
+func WithPrefix(prefix string) BindOption {
+	return func(o *bindOptions) {
+		o.prefix = &prefix
+	}
+}
+
+// WithMethodFilter 只绑定 filter 返回 true 的Go方法
+func WithMethodFilter(filter func(method string) bool) BindOption {
+	return func(o *bindOptions) {
+		o.filter = filter
+	}
+}
+
+// WithMiddleware 只作用于该服务所有action的中间件, 在 ResourceMiddleware 之后执行
+func WithMiddleware(handlerFuncs ...gin.HandlerFunc) BindOption {
+	return func(o *bindOptions) {
+		o.middlewares = append(o.middlewares, handlerFuncs...)
+	}
+}
+
+// WithActionMiddleware 只作用于Go方法 method 对应action的中间件, 在 ActionMiddleware 之后执行
+func WithActionMiddleware(method string, handlerFuncs ...gin.HandlerFunc) BindOption {
+	return func(o *bindOptions) {
+		o.actionMiddlewares[method] = append(o.actionMiddlewares[method], handlerFuncs...)
+	}
+}
+
+// WithUploadLimit Go方法 method 对应action的请求体的最大字节数, 优先于 Config.MaxUploadSize, 0 表示不限制
+func WithUploadLimit(method string, limit int64) BindOption {
+	return func(o *bindOptions) {
+		o.uploadLimits[method] = limit
+	}
+}
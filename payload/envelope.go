@@ -0,0 +1,51 @@
+package payload
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/any"
+)
+
+// Envelope 以 protobuf 编码响应时的包装消息, 与默认的 {code, result, message, error, errors} 结构一致
+//
+//	message Envelope {
+//	  int32 code = 1;
+//	  string message = 2;
+//	  string error = 3;
+//	  google.protobuf.Any result = 4;
+//	  repeated FieldError errors = 5;
+//	}
+type Envelope struct {
+	Code    int32         `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message string        `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Error   string        `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	Result  *any.Any      `protobuf:"bytes,4,opt,name=result,proto3" json:"result,omitempty"`
+	Errors  []*FieldError `protobuf:"bytes,5,rep,name=errors,proto3" json:"errors,omitempty"`
+}
+
+func (m *Envelope) Reset()         { *m = Envelope{} }
+func (m *Envelope) String() string { return proto.CompactTextString(m) }
+func (*Envelope) ProtoMessage()    {}
+
+// FieldError 校验失败的字段
+//
+//	message FieldError {
+//	  string field = 1;
+//	  string tag = 2;
+//	  string param = 3;
+//	  string message = 4;
+//	}
+type FieldError struct {
+	Field   string `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty"`
+	Tag     string `protobuf:"bytes,2,opt,name=tag,proto3" json:"tag,omitempty"`
+	Param   string `protobuf:"bytes,3,opt,name=param,proto3" json:"param,omitempty"`
+	Message string `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+}
+
+func (m *FieldError) Reset()         { *m = FieldError{} }
+func (m *FieldError) String() string { return proto.CompactTextString(m) }
+func (*FieldError) ProtoMessage()    {}
+
+func init() {
+	proto.RegisterType((*Envelope)(nil), "ginrpc.Envelope")
+	proto.RegisterType((*FieldError)(nil), "ginrpc.FieldError")
+}
@@ -2,16 +2,18 @@ package payload
 
 import "net/http"
 
+// Response 服务方法返回该接口时, 可以设置响应的 HTTP 状态码和响应头(包括 Set-Cookie)
 type Response interface {
 	GetCode() int
 	GetErr() error
 	GetHeader() http.Header
 }
 
+// DefaultResponse 可以作为服务方法的返回值, 或者嵌入到返回的结果中, 这些字段不会输出到响应体
 type DefaultResponse struct {
-	Code   int
-	Err    error
-	Header http.Header
+	Code   int         `json:"-" xml:"-" yaml:"-"`
+	Err    error       `json:"-" xml:"-" yaml:"-"`
+	Header http.Header `json:"-" xml:"-" yaml:"-"`
 }
 
 func (d *DefaultResponse) GetCode() int {
@@ -0,0 +1,89 @@
+package ginrpc
+
+import (
+	"reflect"
+
+	"github.com/alphaqiu/ginrpc/payload"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/pkg/errors"
+)
+
+const MIMEProtobuf = binding.MIMEPROTOBUF
+
+// ProtobufCodec 结果必须实现 proto.Message, 默认的响应结构映射为 payload.Envelope, 结果放在 Any 中
+var ProtobufCodec Codec = protobufCodec{}
+
+var protoMessageType = reflect.TypeOf((*proto.Message)(nil)).Elem()
+
+type protobufCodec struct{}
+
+func (protobufCodec) ContentTypes() []string { return []string{MIMEProtobuf, "application/protobuf"} }
+
+func (protobufCodec) Marshal(v interface{}) ([]byte, error) {
+	switch m := v.(type) {
+	case proto.Message:
+		return proto.Marshal(m)
+	case gin.H:
+		env, err := toEnvelope(m)
+		if err != nil {
+			return nil, err
+		}
+		return proto.Marshal(env)
+	default:
+		return nil, errors.Errorf("%T 不是 proto.Message", v)
+	}
+}
+
+func (protobufCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return errors.Errorf("%T 不是 proto.Message", v)
+	}
+	return proto.Unmarshal(data, m)
+}
+
+// supports 只有实现了 proto.Message 的结果才能以 protobuf 编码
+func (protobufCodec) supports(t reflect.Type) bool {
+	return t.Implements(protoMessageType) || reflect.PtrTo(t).Implements(protoMessageType)
+}
+
+// resultChecker Codec 只能编码部分结果类型时实现该接口, 不支持时返回406
+type resultChecker interface {
+	supports(t reflect.Type) bool
+}
+
+// toEnvelope 把 defaultResponse 的响应结构转换为 payload.Envelope
+func toEnvelope(h gin.H) (*payload.Envelope, error) {
+	env := new(payload.Envelope)
+	if code, ok := h["code"].(int); ok {
+		env.Code = int32(code)
+	}
+	env.Message, _ = h["message"].(string)
+	env.Error, _ = h["error"].(string)
+
+	if result, ok := h["result"]; ok && !isNil(result) {
+		m, ok := result.(proto.Message)
+		if !ok {
+			return nil, errors.Errorf("%T 不是 proto.Message", result)
+		}
+
+		var err error
+		if env.Result, err = ptypes.MarshalAny(m); err != nil {
+			return nil, err
+		}
+	}
+
+	fields, _ := h["errors"].([]ValidationError)
+	for _, field := range fields {
+		env.Errors = append(env.Errors, &payload.FieldError{
+			Field:   field.Field,
+			Tag:     field.Tag,
+			Param:   field.Param,
+			Message: field.Message,
+		})
+	}
+	return env, nil
+}
@@ -0,0 +1,69 @@
+package ginrpc
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// ResponseRenderer 输出action的结果和错误, 包括绑定入參失败、Before 拒绝和 panic.
+// status 为建议的 HTTP 状态码, result 和 err 都可能为nil
+type ResponseRenderer interface {
+	Render(ctx *gin.Context, status int, result interface{}, err Err)
+}
+
+// ResponseRendererFunc 把函数转换为 ResponseRenderer
+type ResponseRendererFunc func(ctx *gin.Context, status int, result interface{}, err Err)
+
+func (f ResponseRendererFunc) Render(ctx *gin.Context, status int, result interface{}, err Err) {
+	f(ctx, status, result, err)
+}
+
+// DefaultRenderer 以 {code, result, message, error} 的格式输出, 校验失败的字段在 errors 中, 编码由 Accept 决定
+var DefaultRenderer ResponseRenderer = ResponseRendererFunc(defaultResponse)
+
+func defaultResponse(ctx *gin.Context, status int, data interface{}, resp Err) {
+	ret := gin.H{}
+	if resp == nil && data == nil {
+		Respond(ctx, status, gin.H{"code": 200})
+		return
+	}
+
+	if data != nil {
+		ret["result"] = data
+	}
+
+	var (
+		code    int
+		message string
+		errMsg  string
+	)
+	if resp != nil {
+		code = resp.Code()
+		message = Translate(ctx, resp.Message())
+		errMsg = resp.Error()
+	}
+
+	if code > 0 {
+		ret["code"] = code
+	} else if code <= 0 && data != nil {
+		ret["code"] = 200
+	}
+
+	if message != "" {
+		ret["message"] = message
+	}
+
+	if errMsg != "" {
+		ret["error"] = errMsg
+	}
+
+	if be, ok := resp.(*BindError); ok && len(be.Fields) > 0 {
+		ret["errors"] = be.Fields
+	}
+
+	if len(ret) == 0 {
+		Respond(ctx, status, gin.H{"code": 200})
+		return
+	}
+
+	Respond(ctx, status, ret)
+}
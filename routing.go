@@ -0,0 +1,275 @@
+package ginrpc
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultMethodPrefixes 方法名称前缀与 HTTP Method 的对应关系, 没有匹配前缀的方法为 POST
+var DefaultMethodPrefixes = map[string]string{
+	"Get":     http.MethodGet,
+	"Head":    http.MethodHead,
+	"Put":     http.MethodPut,
+	"Patch":   http.MethodPatch,
+	"Delete":  http.MethodDelete,
+	"Options": http.MethodOptions,
+}
+
+var defaultRouteParser = NewRouteParser(nil)
+
+// ParseMethodName 使用默认的前缀规则, 由服务结构体名称和方法名称推导出 HTTP Method, 资源名称和action名称
+func ParseMethodName(resource, method string) (string, string, string) {
+	return defaultRouteParser.Parse(resource, method)
+}
+
+// RouteSpec 覆盖方法的路由. Method 为空时使用前缀推导的 HTTP Method, 转换为大写后只能包含字母; Path 为空时使用推导的action,
+// 以 / 开头时为 UrlPrefix 之后的完整路径, 否则替换action. 路径参数需要在 Path 中以 :param 声明
+type RouteSpec struct {
+	Method string
+	Path   string
+}
+
+// Resolve 返回覆盖后的 HTTP Method 和 UrlPrefix 之后的路径
+func (s RouteSpec) Resolve(method, version, resource, action string) (string, string) {
+	if len(s.Method) > 0 {
+		method = strings.ToUpper(s.Method)
+	}
+
+	if strings.HasPrefix(s.Path, "/") {
+		return method, s.Path
+	}
+
+	if len(s.Path) > 0 {
+		action = s.Path
+	}
+	return method, "/" + strings.Join([]string{version, resource, action}, "/")
+}
+
+// httpMethodPattern 与 gin 注册路由时的检查一致, 无效的 HTTP Method 会使 gin panic
+var httpMethodPattern = regexp.MustCompile("^[A-Z]+$")
+
+func validMethod(method string) bool {
+	return httpMethodPattern.MatchString(method)
+}
+
+type methodPrefix struct {
+	prefix string
+	verb   string
+}
+
+// RouteParser 由方法名称前缀推导 HTTP Method, 并使用 NamingStrategy 生成资源和action名称,
+// 服务端和客户端使用相同的配置保证路由一致
+type RouteParser struct {
+	prefixes []methodPrefix
+	naming   NamingStrategy
+}
+
+// NewRouteParser 在 DefaultMethodPrefixes 的基础上合并 Config.MethodPrefixes, Config.Naming 默认为 LowerCaseNaming
+func NewRouteParser(cnf *Config) *RouteParser {
+	var naming NamingStrategy = LowerCaseNaming
+	table := make(map[string]string, len(DefaultMethodPrefixes))
+	for prefix, verb := range DefaultMethodPrefixes {
+		table[prefix] = verb
+	}
+
+	if cnf != nil {
+		if cnf.Naming != nil {
+			naming = cnf.Naming
+		}
+
+		for prefix, verb := range cnf.MethodPrefixes {
+			verb = strings.ToUpper(strings.TrimSpace(verb))
+			if len(prefix) == 0 || !validMethod(verb) {
+				log.Warnf("忽略无效的方法前缀配置: %q -> %q", prefix, verb)
+				continue
+			}
+			table[prefix] = verb
+		}
+	}
+
+	p := &RouteParser{prefixes: make([]methodPrefix, 0, len(table)), naming: naming}
+	for prefix, verb := range table {
+		p.prefixes = append(p.prefixes, methodPrefix{prefix: prefix, verb: verb})
+	}
+
+	// 最长的前缀优先匹配, 例如同时配置了 Get 和 GetAll
+	sort.Slice(p.prefixes, func(i, j int) bool {
+		if len(p.prefixes[i].prefix) != len(p.prefixes[j].prefix) {
+			return len(p.prefixes[i].prefix) > len(p.prefixes[j].prefix)
+		}
+		return p.prefixes[i].prefix < p.prefixes[j].prefix
+	})
+	return p
+}
+
+// Parse return http method name/resource name/ action name
+func (p *RouteParser) Parse(resource, method string) (string, string, string) {
+	verb, action, _ := p.parse(method)
+	return verb, p.naming.Name(resource), p.naming.Name(action)
+}
+
+// Ambiguous 方法名称以某个前缀开头, 但前缀之后不是新的单词, 例如 Getaway 或 Header,
+// 这类方法保持早期版本的路由, 返回有歧义的前缀
+func (p *RouteParser) Ambiguous(method string) (string, bool) {
+	_, _, ambiguous := p.parse(method)
+	return ambiguous, len(ambiguous) > 0
+}
+
+func (p *RouteParser) parse(method string) (verb, action, ambiguous string) {
+	for _, item := range p.prefixes {
+		if !strings.HasPrefix(method, item.prefix) {
+			continue
+		}
+
+		rest := method[len(item.prefix):]
+		// 方法名称只有前缀时作为action, 例如 Delete -> POST /delete
+		if len(rest) == 0 {
+			continue
+		}
+		if isWordBoundary(rest) {
+			return item.verb, rest, ambiguous
+		}
+
+		if len(ambiguous) == 0 {
+			ambiguous = item.prefix
+		}
+	}
+
+	if len(ambiguous) > 0 {
+		verb, action = legacyParse(method)
+		return verb, action, ambiguous
+	}
+	return http.MethodPost, method, ""
+}
+
+// legacyParse 早期版本只识别 Get 和 Options 前缀并且不判断单词边界, 有歧义的方法名称按照该规则保持原来的路由,
+// 例如 Getaway -> GET /away, Header -> POST /header
+func legacyParse(method string) (string, string) {
+	for prefix, verb := range map[string]string{"Get": http.MethodGet, "Options": http.MethodOptions} {
+		if strings.HasPrefix(method, prefix) && len(method) > len(prefix) {
+			return verb, method[len(prefix):]
+		}
+	}
+	return http.MethodPost, method
+}
+
+func isWordBoundary(rest string) bool {
+	r := []rune(rest)[0]
+	return unicode.IsUpper(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// NamingStrategy 把Go的结构体名称和方法名称转换为路由中的资源和action名称
+type NamingStrategy interface {
+	Name(identifier string) string
+}
+
+// NamingFunc 函数形式的 NamingStrategy
+type NamingFunc func(identifier string) string
+
+func (f NamingFunc) Name(identifier string) string {
+	return f(identifier)
+}
+
+var (
+	// LowerCaseNaming RemoveAll -> removeall
+	LowerCaseNaming NamingStrategy = NamingFunc(strings.ToLower)
+	// KebabCaseNaming RemoveAll -> remove-all
+	KebabCaseNaming NamingStrategy = NamingFunc(func(identifier string) string {
+		return strings.ToLower(strings.Join(splitWords(identifier), "-"))
+	})
+	// SnakeCaseNaming RemoveAll -> remove_all
+	SnakeCaseNaming NamingStrategy = NamingFunc(func(identifier string) string {
+		return strings.ToLower(strings.Join(splitWords(identifier), "_"))
+	})
+	// CamelCaseNaming RemoveAll -> removeAll
+	CamelCaseNaming NamingStrategy = NamingFunc(func(identifier string) string {
+		words := splitWords(identifier)
+		for i, word := range words {
+			word = strings.ToLower(word)
+			if i > 0 {
+				r := []rune(word)
+				r[0] = unicode.ToUpper(r[0])
+				word = string(r)
+			}
+			words[i] = word
+		}
+		return strings.Join(words, "")
+	})
+)
+
+// splitWords 按照Go的命名习惯拆分单词, 连续的大写字母作为一个缩写词, 例如 HTTPServer -> HTTP, Server
+func splitWords(identifier string) []string {
+	words := make([]string, 0, 4)
+	word := make([]rune, 0, len(identifier))
+	runes := []rune(identifier)
+	for i, r := range runes {
+		if r == '_' || r == '-' {
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = word[:0]
+			}
+			continue
+		}
+
+		if len(word) > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				words = append(words, string(word))
+				word = word[:0]
+			}
+		}
+		word = append(word, r)
+	}
+
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+// routeTable 记录已绑定的路由以及对应的Go方法, 在 Bind 时发现 gin 注册路由时才会 panic 的冲突
+type routeTable map[string][]routeEntry
+
+type routeEntry struct {
+	path  string
+	owner string
+}
+
+func (t routeTable) add(method, path, owner string) {
+	t[method] = append(t[method], routeEntry{path: path, owner: owner})
+}
+
+// conflict 返回与 method path 冲突的路由所属的Go方法
+func (t routeTable) conflict(method, path string) (string, bool) {
+	for _, entry := range t[method] {
+		if routeConflict(entry.path, path) {
+			return entry.owner, true
+		}
+	}
+	return "", false
+}
+
+// routeConflict 按照 gin 路由树的规则逐段比较两个路径:
+// 完全相同的路径, 同一位置名称不同的路径参数, 以及通配符与其他路径段都会冲突
+func routeConflict(a, b string) bool {
+	sa := strings.Split(a, "/")
+	sb := strings.Split(b, "/")
+	for i := 0; i < len(sa) && i < len(sb); i++ {
+		if sa[i] == sb[i] {
+			continue
+		}
+
+		if strings.HasPrefix(sa[i], "*") || strings.HasPrefix(sb[i], "*") {
+			return true
+		}
+
+		// 路径参数与静态路径可以共存
+		return strings.HasPrefix(sa[i], ":") && strings.HasPrefix(sb[i], ":")
+	}
+
+	return len(sa) == len(sb)
+}
@@ -3,7 +3,9 @@ package ginrpc
 import (
 	"context"
 	"fmt"
+	"github.com/alphaqiu/ginrpc/payload"
 	"github.com/gin-gonic/gin"
+	"github.com/golang/protobuf/proto"
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/pkg/errors"
 	"net/http"
@@ -12,6 +14,8 @@ import (
 	"reflect"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -22,56 +26,82 @@ func New(cnf *Config) APIServer {
 		cnf = defaultConfig()
 	}
 
-	r := gin.New()
 	httpServer := &http.Server{
 		Addr:         cnf.Addr,
-		Handler:      r,
 		ReadTimeout:  cnf.ReadTimeout,
 		WriteTimeout: cnf.WriteTimout,
 		IdleTimeout:  cnf.IdleTimeout,
+		// 流式响应通过连接取消 WriteTimeout
+		ConnContext: ConnContext,
 	}
 
 	if cnf.KeepAlive {
 		httpServer.SetKeepAlivesEnabled(true)
 	}
 
-	return &ginServer{cnf: cnf, router: r, httpServer: httpServer, quit: make(chan struct{})}
+	g := &ginServer{
+		cnf:        cnf,
+		httpServer: httpServer,
+		parser:     NewRouteParser(cnf),
+		i18n:       newTranslator(cnf),
+		codecs:     newCodecRegistry(cnf.Codecs),
+		quit:       make(chan struct{}),
+	}
+	g.routes = g.builtinRoutes()
+	return g
 }
 
 type APIServer interface {
 	Start(sig ...os.Signal) <-chan os.Signal
 	Stop(ctx context.Context) error
 	BindPreInterceptor(handlerFuncs ...gin.HandlerFunc)
-	Bind(interface{}) error
+	Bind(service interface{}, opts ...BindOption) error
+	// Unbind 移除以相同的 WithName/WithVersion/WithPrefix 绑定的服务, 同一实例的其他绑定不受影响, 正在处理的请求不受影响
+	Unbind(service interface{}, opts ...BindOption) error
 	BindPostInterceptor(handlerFuncs ...gin.HandlerFunc)
+	// Handler 返回注册了所有服务的 http.Handler, 可以交给自己的 http.Server 或者 httptest 使用.
+	// 之后的 Bind/Unbind 会重新生成路由并原子地替换
+	Handler() http.Handler
+	// Mount 把绑定的服务和内置接口注册到调用方的 gin.Engine 或 gin.RouterGroup, 不启动内置的监听.
+	// gin 不能移除已注册的路由, 之后的 Bind/Unbind 不会作用于已挂载的 router
+	Mount(router gin.IRouter)
 }
 
 type ginServer struct {
 	cnf              *Config
-	router           *gin.Engine
 	httpServer       *http.Server
+	parser           *RouteParser
+	i18n             *translator
+	codecs           *codecRegistry
+	mu               sync.RWMutex // 保护以下字段
 	preInterceptors  []gin.HandlerFunc
 	postInterceptors []gin.HandlerFunc
 	services         []serviceMap
+	routes           routeTable
+	serving          bool
+	engine           atomic.Value // *gin.Engine, 由 rebuild 替换
 	quit             chan struct{}
 }
 
 func (g *ginServer) BindPreInterceptor(handlerFuncs ...gin.HandlerFunc) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	g.preInterceptors = append(g.preInterceptors, handlerFuncs...)
+	g.rebuild()
 }
 
 func (g *ginServer) BindPostInterceptor(handlerFuncs ...gin.HandlerFunc) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	g.postInterceptors = append(g.postInterceptors, handlerFuncs...)
+	g.rebuild()
 }
 
 func (g *ginServer) Start(sig ...os.Signal) <-chan os.Signal {
 	gin.SetMode(g.cnf.RunMode)
-	ch := make(chan os.Signal)
-
-	g.router.Use(g.preInterceptors...)
-	g.makeRoutes()
-	g.router.Use(g.postInterceptors...)
+	ch := make(chan os.Signal, 1)
 
+	g.httpServer.Handler = g.Handler()
 	go g.listenAndServe()
 	signal.Notify(ch, sig...)
 	return ch
@@ -134,34 +164,66 @@ func (g *ginServer) Stop(ctx context.Context) error {
 	return nil
 }
 
-func (g *ginServer) Bind(service interface{}) error {
-	// 结构体名称作为资源名称，方法默认都是POST，如果前缀为Get，则是Get，前缀为Options 则是Options
+func (g *ginServer) Bind(service interface{}, opts ...BindOption) error {
+	// 结构体名称作为资源名称，方法默认都是POST，前缀为Get/Head/Put/Patch/Delete/Options 则使用对应的HTTP Method
+	// 前缀可以通过 Config.MethodPrefixes 扩展
 	// action=去掉前缀的方法名
 	// 入參支持绑定JSON和Query，如果入參结构体后缀为Query，则以Query方式解析
+	// 入參结构体后缀为Path，则以uri标签绑定路由参数，路由为 version/resource/action/:param
 	// 出參最多支持3个参数，最后一个参数必须是error，或者实现了error接口的结构体
-	version := "v0"
-	if vo, ok := service.(ResourceVersion); ok {
-		version = strings.ReplaceAll(strings.Trim(vo.Version(), " "), " ", "_")
-	}
-
+	options := newBindOptions(opts)
 	svcRef := reflect.ValueOf(service)
 	if reflect.Ptr != svcRef.Kind() || svcRef.Elem().Kind() != reflect.Struct {
 		return errors.Wrapf(invalidInstanceErr, "%+v", svcRef)
 	}
+	binding := g.bindingOf(service, options)
+	version, urlPrefix := binding.version, binding.prefix
 	log.Debugf("开始绑定服务: %+v", svcRef.Type())
 	start := time.Now()
 
-	actions := make(map[string]*actionInOutParams)
+	// 服务声明的路由覆盖，在方法名称推导之前生效
+	interceptor, _ := service.(ResourceInterceptor)
+	reserved := reservedMethods(service)
+	routes := make(map[string]RouteSpec)
+	if rr, ok := service.(ResourceRoutes); ok {
+		for name, spec := range rr.Routes() {
+			if _, ok := svcRef.Type().MethodByName(name); !ok {
+				return errors.Wrapf(invalidRouteErr, "%s.%s 方法不存在", svcRef.Elem().Type().Name(), name)
+			}
+			routes[name] = spec
+		}
+	}
+
+	ignored := make(map[string]bool)
+	if ri, ok := service.(ResourceIgnore); ok {
+		for _, name := range ri.Ignore() {
+			ignored[name] = true
+		}
+	}
+
+	// rejected 签名无效而被跳过的方法, StrictBind 时作为错误返回
+	var rejected []string
+	actions := make([]*actionInOutParams, 0, svcRef.NumMethod())
 	for f := 0; f < svcRef.NumMethod(); f++ {
 		methodInst := svcRef.Method(f)
 		methodDef := svcRef.Type().Method(f)
+		if reserved[methodDef.Name] || ignored[methodDef.Name] {
+			continue
+		}
+
+		if options.filter != nil && !options.filter(methodDef.Name) {
+			continue
+		}
 		//if !method.IsExported() {
 		//	log.Debugf("[1]非Service方法. 无效的方法. 方法签名: %s", method.Type)
 		//	continue
 		//}
 
-		reqMethod, resourceName, actionName := parseMethodName(svcRef.Elem().Type(), methodDef.Name)
-		log.Debugf("HTTP Method: %s, %s/%s/%s", reqMethod, g.cnf.UrlPrefix, resourceName, actionName)
+		reqMethod, resourceName, actionName := g.parser.Parse(svcRef.Elem().Type().Name(), methodDef.Name)
+		if len(options.name) > 0 {
+			resourceName = options.name
+		}
+		log.Debugf("HTTP Method: %s, %s/%s/%s", reqMethod, urlPrefix, resourceName, actionName)
 
 		// contentParam: body 内部的数据绑定，可以是application/json,可以是multipart/form-data,可以是application/x-www-form-urlencoded
 		// 也可以是ProtoBuf和msgPack 消息格式。由Header: Content-Type 决定
@@ -179,30 +241,146 @@ func (g *ginServer) Bind(service interface{}) error {
 		// func(queryParam, header) ginrpc.Response
 		// func(queryParam, contentParam, header) ginrpc.Response
 		// func(contentParam, header) ginrpc.Response
-		numOutParams, ok := g.checkOutParams(methodDef.Type, methodInst)
-		if !ok {
+		spec, override := routes[methodDef.Name]
+		numOutParams, err := g.checkOutParams(methodDef.Type, methodInst)
+		var actionInOutParam *actionInOutParams
+		if err == nil {
+			actionInOutParam, err = g.initInParams(methodDef, methodInst)
+		}
+		if err == nil && actionInOutParam.Socket != nil && (numOutParams != 2 || !isStreamResult(methodDef.Type.Out(0))) {
+			err = errors.Errorf("入參为 %s 的方法应返回 (<-chan T, error)", actionInOutParam.Socket)
+		}
+
+		if err != nil {
+			if override {
+				return errors.Wrapf(invalidRouteErr, "%s.%s 不是有效的服务方法: %s",
+					svcRef.Elem().Type().Name(), methodDef.Name, methodInst.Type())
+			}
+			rejected = append(rejected, fmt.Sprintf("%s(%s): %v", methodDef.Name, methodInst.Type(), err))
 			continue
 		}
 
-		if actionInOutParam := g.initInParams(methodDef, methodInst); actionInOutParam != nil {
-			actionInOutParam.ReqMethod = reqMethod
-			actionInOutParam.ResourceName = resourceName
-			actionInOutParam.OutParamNum = numOutParams
-			actionInOutParam.Fn = methodInst
-			actionInOutParam.ActionName = actionName
-			actions[methodDef.Name] = actionInOutParam
+		// 有歧义的方法名称保持原来的路由, StrictBind 时作为错误返回
+		if prefix, ok := g.parser.Ambiguous(methodDef.Name); ok && !override {
+			log.Warnf("方法 %s.%s 以前缀 %s 开头但不是完整的单词, 路由为 %s %s, 可以通过 ResourceRoutes 声明路由",
+				svcRef.Elem().Type().Name(), methodDef.Name, prefix, reqMethod, actionName)
+			rejected = append(rejected, fmt.Sprintf("%s(%s): 以前缀 %s 开头但不是完整的单词, 可以通过 ResourceRoutes 声明路由",
+				methodDef.Name, methodInst.Type(), prefix))
+		}
+
+		if actionInOutParam.Socket != nil {
+			// WebSocket 握手只能使用 GET
+			reqMethod = http.MethodGet
+		}
+		actionInOutParam.ReqMethod = reqMethod
+		actionInOutParam.ResourceName = resourceName
+		actionInOutParam.OutParamNum = numOutParams
+		actionInOutParam.Fn = methodInst
+		actionInOutParam.ActionName = actionName
+		actionInOutParam.MethodName = methodDef.Name
+		actionInOutParam.Version = version
+		actionInOutParam.Interceptor = interceptor
+		actionInOutParam.UploadLimit = g.cnf.MaxUploadSize
+		if limit, ok := options.uploadLimits[methodDef.Name]; ok {
+			actionInOutParam.UploadLimit = limit
+		}
+		if numOutParams == 2 {
+			actionInOutParam.Result = methodDef.Type.Out(0)
+			actionInOutParam.Stream = isStreamResult(actionInOutParam.Result)
+		}
+
+		var p string
+		actionInOutParam.ReqMethod, p = spec.Resolve(reqMethod, version, resourceName, actionName)
+		if !validMethod(actionInOutParam.ReqMethod) {
+			return errors.Wrapf(invalidRouteErr, "%s.%s 的 HTTP Method %q 无效",
+				svcRef.Elem().Type().Name(), methodDef.Name, actionInOutParam.ReqMethod)
+		}
+		if len(spec.Path) > 0 && !strings.HasPrefix(spec.Path, "/") {
+			actionInOutParam.ActionName = spec.Path
+		}
+		if len(spec.Path) == 0 {
+			for _, name := range actionInOutParam.PathParams {
+				p += "/:" + name
+			}
+		} else {
+			for _, name := range actionInOutParam.PathParams {
+				if !strings.Contains(p+"/", "/:"+name+"/") {
+					return errors.Wrapf(invalidRouteErr, "%s.%s 的路径 %s 缺少路径参数 :%s",
+						svcRef.Elem().Type().Name(), methodDef.Name, spec.Path, name)
+				}
+			}
+		}
+		actionInOutParam.RelativePath = urlPrefix + p
+		actions = append(actions, actionInOutParam)
+	}
+
+	if g.cnf.StrictBind && len(rejected) > 0 {
+		return errors.Wrapf(rejectedMethodsErr, "%s 有%d个方法签名无效, 不需要绑定的方法可以通过 ResourceIgnore 忽略: %s",
+			svcRef.Type(), len(rejected), strings.Join(rejected, "; "))
+	}
+
+	// 服务级别和action级别的中间件, 只作用于该服务的路由
+	var middlewares []gin.HandlerFunc
+	if rm, ok := service.(ResourceMiddleware); ok {
+		middlewares = append(middlewares, rm.Middlewares()...)
+	}
+	middlewares = append(middlewares, options.middlewares...)
+
+	actionMiddlewares := make(map[string][]gin.HandlerFunc)
+	if am, ok := service.(ActionMiddleware); ok {
+		for name, handlerFuncs := range am.ActionMiddlewares() {
+			actionMiddlewares[name] = append(actionMiddlewares[name], handlerFuncs...)
+		}
+	}
+
+	for name, handlerFuncs := range options.actionMiddlewares {
+		actionMiddlewares[name] = append(actionMiddlewares[name], handlerFuncs...)
+	}
+
+	for name := range actionMiddlewares {
+		if _, ok := svcRef.Type().MethodByName(name); !ok || reserved[name] {
+			return errors.Wrapf(invalidMiddlewareErr, "%s.%s 不是服务方法", svcRef.Elem().Type().Name(), name)
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// 先检查所有的路由, 存在冲突时不绑定该服务的任何action
+	pending := make(routeTable)
+	for _, inOutParams := range actions {
+		owner := fmt.Sprintf("%s.%s", svcRef.Elem().Type(), inOutParams.MethodName)
+		existing, ok := g.routes.conflict(inOutParams.ReqMethod, inOutParams.RelativePath)
+		if !ok {
+			existing, ok = pending.conflict(inOutParams.ReqMethod, inOutParams.RelativePath)
 		}
+		if ok {
+			return errors.Wrapf(routeConflictErr, "%s %s: %s 与 %s",
+				inOutParams.ReqMethod, inOutParams.RelativePath, owner, existing)
+		}
+		pending.add(inOutParams.ReqMethod, inOutParams.RelativePath, owner)
+	}
+
+	for method, entries := range pending {
+		g.routes[method] = append(g.routes[method], entries...)
 	}
 
 	for _, inOutParams := range actions {
 		handler := g.assignHandler(inOutParams)
-		relativePath := g.relativePath(version, inOutParams.ResourceName, inOutParams.ActionName)
+		relativePath := inOutParams.RelativePath
+		inOutParams.Info = newActionInfo(relativePath, inOutParams)
 		g.services = append(g.services, serviceMap{
+			Binding:      binding,
+			Owner:        fmt.Sprintf("%s.%s", svcRef.Elem().Type(), inOutParams.MethodName),
 			Method:       inOutParams.ReqMethod,
 			RelativePath: relativePath,
+			Group:        middlewares,
+			Middlewares:  actionMiddlewares[inOutParams.MethodName],
 			Func:         handler,
+			Params:       inOutParams,
 		})
 	}
+	g.rebuild()
 
 	log.Debugf("服务绑定完成: %s 核计绑定了%d个服务接口, time elapsed: %s",
 		svcRef.Type(),
@@ -211,52 +389,180 @@ func (g *ginServer) Bind(service interface{}) error {
 	return nil
 }
 
-func (g *ginServer) makeRoutes() {
-	for _, item := range g.services {
-		log.Debugf("Method: %s, Path: %s", item.Method, item.RelativePath)
-		g.router.Handle(item.Method, item.RelativePath, item.Func)
+// reservedMethods 服务实现的框架接口的方法不作为服务接口绑定
+func reservedMethods(service interface{}) map[string]bool {
+	reserved := make(map[string]bool)
+	for _, iface := range reservedInterfaces {
+		if reflect.TypeOf(service).Implements(iface) {
+			for i := 0; i < iface.NumMethod(); i++ {
+				reserved[iface.Method(i).Name] = true
+			}
+		}
+	}
+	return reserved
+}
+
+// bindingKey 一次绑定的标识. 不比较实例的指针, 零大小的结构体的不同实例可能有相同的地址
+type bindingKey struct {
+	typ     reflect.Type
+	prefix  string
+	version string
+	name    string
+}
+
+// bindingOf 按照 Bind 的规则解析服务的 UrlPrefix, 版本和资源名称
+func (g *ginServer) bindingOf(service interface{}, options *bindOptions) bindingKey {
+	version := "v0"
+	if vo, ok := service.(ResourceVersion); ok {
+		version = vo.Version()
+	}
+
+	if len(options.version) > 0 {
+		version = options.version
+	}
+	version = strings.ReplaceAll(strings.Trim(version, " "), " ", "_")
+
+	urlPrefix := g.cnf.UrlPrefix
+	if options.prefix != nil {
+		urlPrefix = *options.prefix
 	}
 
-	api := "/exports"
-	if len(g.cnf.UrlPrefix) > 0 {
-		api = g.cnf.UrlPrefix + api
+	t := reflect.TypeOf(service)
+	name := options.name
+	if len(name) == 0 && t.Kind() == reflect.Ptr {
+		name = g.parser.naming.Name(t.Elem().Name())
 	}
+	return bindingKey{typ: t, prefix: urlPrefix, version: version, name: name}
+}
+
+func (g *ginServer) Unbind(service interface{}, opts ...BindOption) error {
+	binding := g.bindingOf(service, newBindOptions(opts))
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
 
-	g.router.Handle(http.MethodGet, api, func(c *gin.Context) {
-		apis := make([]string, len(g.services))
-		for idx, item := range g.services {
-			apis[idx] = item.RelativePath
+	// 生成新的切片, 正在读取旧切片的 exports 不受影响
+	services := make([]serviceMap, 0, len(g.services))
+	for _, item := range g.services {
+		if item.Binding != binding {
+			services = append(services, item)
 		}
+	}
+
+	if len(services) == len(g.services) {
+		return errors.Wrapf(notBoundErr, "%T %s/%s/%s", service, binding.prefix, binding.version, binding.name)
+	}
+
+	g.services = services
+	g.routes = g.builtinRoutes()
+	for _, item := range g.services {
+		g.routes.add(item.Method, item.RelativePath, item.Owner)
+	}
+	g.rebuild()
+	return nil
+}
+
+// builtinRoutes 内置接口与服务的路由使用同一个路由表
+func (g *ginServer) builtinRoutes() routeTable {
+	routes := make(routeTable)
+	routes.add(http.MethodGet, g.exportsPath(), "内置接口 exports")
+	routes.add(http.MethodGet, g.openAPIPath(), "内置接口 openapi")
+	return routes
+}
+
+func (g *ginServer) Handler() http.Handler {
+	g.mu.Lock()
+	if !g.serving {
+		g.serving = true
+		g.rebuild()
+	}
+	g.mu.Unlock()
+
+	// 每个请求使用当时的 gin.Engine, 替换之后正在处理的请求仍然由旧的 gin.Engine 完成
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.engine.Load().(*gin.Engine).ServeHTTP(w, r)
+	})
+}
+
+// rebuild 由当前绑定的服务生成新的 gin.Engine 并原子地替换, 调用方需要持有 g.mu
+func (g *ginServer) rebuild() {
+	if !g.serving {
+		return
+	}
+
+	engine := gin.New()
+	// 按照转义的路径匹配, 路径参数可以包含 "/", 例如客户端发送的 a%2Fb
+	engine.UseRawPath = true
+	engine.Use(g.preInterceptors...)
+	g.makeRoutes(engine, g.services)
+	// 在注册路由之后添加, 只作用于 404/405
+	engine.Use(g.postInterceptors...)
+	g.engine.Store(engine)
+}
 
-		c.JSON(http.StatusOK, gin.H{"apis": apis})
+// Mount 时 preInterceptors 只作用于ginrpc的路由, postInterceptors 属于调用方 gin.Engine 的 NoRoute, 不会注册
+func (g *ginServer) Mount(router gin.IRouter) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	g.makeRoutes(router.Group("", g.preInterceptors...), g.services)
+}
+
+// snapshot 返回当前绑定的服务, 用于 exports 和 openapi
+func (g *ginServer) snapshot() []serviceMap {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.services
+}
+
+func (g *ginServer) makeRoutes(router gin.IRouter, services []serviceMap) {
+	for _, item := range services {
+		log.Debugf("Method: %s, Path: %s", item.Method, item.RelativePath)
+		handlers := make([]gin.HandlerFunc, 0, len(item.Middlewares)+1)
+		handlers = append(handlers, item.Middlewares...)
+		handlers = append(handlers, item.Func)
+		router.Group("", item.Group...).Handle(item.Method, item.RelativePath, handlers...)
+	}
+
+	router.Handle(http.MethodGet, g.exportsPath(), g.exports)
+
+	router.Handle(http.MethodGet, g.openAPIPath(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, g.openAPI())
 	})
 }
 
-func (g *ginServer) checkOutParams(methodType reflect.Type, methodInst reflect.Value) (numParams int, ok bool) {
+func (g *ginServer) exportsPath() string {
+	return g.cnf.UrlPrefix + "/exports"
+}
+
+func (g *ginServer) openAPIPath() string {
+	return g.cnf.UrlPrefix + "/openapi.json"
+}
+
+func (g *ginServer) checkOutParams(methodType reflect.Type, methodInst reflect.Value) (int, error) {
 	outCount := methodType.NumOut()
 	if outCount < 1 || outCount > 2 {
 		// 不符合service的方法，结构体可以定义非服务方法，这类方法过滤，不返回错误
 		log.Debugf("[0-1]非Service方法，无效的返回值. return params: %d, %v", outCount, methodType)
-		return
+		return 0, errors.Errorf("返回值数量为%d, 应为1或2个", outCount)
 	}
 
 	item := methodType.Out(outCount - 1)
 	kind := item.Kind()
 	if kind != reflect.Interface && !item.Implements(errInterface) {
 		log.Debugf("[0-2]非Service方法, 无效的返回值. 最后一个参数不是实现了error的接口: Kind: %v, 方法签名: %s", kind, methodInst.Type())
-		return
+		return 0, errors.Errorf("最后一个返回值 %s 没有实现error接口", item)
 	}
 
 	if outCount == 2 {
 		item = methodType.Out(0)
-		if !g.checkResultParam(item) {
-			// 当返回值=2时，第一个参数必须时结构体或者Slice
-			log.Debugf("[0-3]非Service方法, 无效的返回值. 返回的第一个参数不是结构体或者Slice: %v", item.Kind())
-			return
+		if !g.checkResultParam(item) && !isStreamResult(item) {
+			// 当返回值=2时，第一个参数必须时结构体, Slice或者可接收的channel
+			log.Debugf("[0-3]非Service方法, 无效的返回值. 返回的第一个参数不是结构体, Slice或者channel: %v", item.Kind())
+			return 0, errors.Errorf("第一个返回值 %s 不是结构体, Slice或者<-chan", item)
 		}
-		return 2, true
+		return 2, nil
 	}
-	return 1, true
+	return 1, nil
 }
 
 func (g *ginServer) checkResultParam(item reflect.Type) bool {
@@ -273,23 +579,35 @@ func (g *ginServer) checkResultParam(item reflect.Type) bool {
 	return true
 }
 
-func (g *ginServer) initInParams(method reflect.Method, methodInst reflect.Value) *actionInOutParams {
+func (g *ginServer) initInParams(method reflect.Method, methodInst reflect.Value) (*actionInOutParams, error) {
 	inCount := method.Type.NumIn()
-	if inCount < 2 || inCount > 5 { // 包含方法所属自身引用
+	if inCount < 2 || inCount > 6 { // 包含方法所属自身引用
 		log.Debugf("[1]非Service方法. 无效的入參. 方法签名: %s", methodInst.Type())
-		return nil
+		return nil, errors.Errorf("入參数量为%d, 应为Context和最多4个参数", inCount-1)
 	}
 
 	param := method.Type.In(1)
 	ctxRef := reflect.TypeOf((*context.Context)(nil)).Elem()
 	if param.Kind() != reflect.Interface && !param.Implements(ctxRef) {
 		log.Debugf("[2]非Service方法. 无效的入參.第一个参数应为Context: Kind: %s, 方法签名: %s", param.Kind(), methodInst.Type())
-		return nil
+		return nil, errors.Errorf("第一个入參 %s 不是context.Context", param)
 	}
 
 	inParam := new(actionInOutParams)
 	for p := 2; p < method.Type.NumIn(); p++ {
 		param = method.Type.In(p)
+		if isUploadParam(param) {
+			inParam.Upload = param
+			inParam.UploadIndex = p - 1
+			continue
+		}
+
+		if isSocketParam(param) {
+			inParam.Socket = param
+			inParam.SocketIndex = p - 1
+			continue
+		}
+
 		if param.Kind() == reflect.Ptr {
 			param = param.Elem()
 		}
@@ -297,12 +615,18 @@ func (g *ginServer) initInParams(method reflect.Method, methodInst reflect.Value
 		isHeader := g.isHttpHeaderSignature(param)
 		if param.Kind() != reflect.Struct && !isHeader {
 			log.Debugf("[3]非Service方法. 无效的入參. 方法签名: %s", methodInst.Type())
-			return nil
+			return nil, errors.Errorf("入參 %s 不是结构体或者http.Header", method.Type.In(p))
 		}
 
 		if isHeader {
 			inParam.HasHeader = true
 			inParam.HeaderIndex = p - 1
+		} else if strings.HasSuffix(param.Name(), "Path") {
+			inParam.Path = param
+			inParam.PathKind = method.Type.In(p).Kind()
+			inParam.PathIndex = p - 1
+			inParam.PathParams = uriParams(param)
+			inParam.HasPath = true
 		} else if strings.HasSuffix(param.Name(), "Query") {
 			inParam.Query = param
 			inParam.QueryKind = method.Type.In(p).Kind()
@@ -316,7 +640,13 @@ func (g *ginServer) initInParams(method reflect.Method, methodInst reflect.Value
 		}
 	}
 
-	return inParam
+	if inParam.Socket != nil && inParam.HasBody {
+		return nil, errors.Errorf("入參 %s 与请求体 %s 不能同时使用", inParam.Socket, inParam.Body)
+	}
+	if inParam.Upload != nil && (inParam.HasBody || inParam.Socket != nil) {
+		return nil, errors.Errorf("入參 %s 不能与请求体或者 <-chan 同时使用", inParam.Upload)
+	}
+	return inParam, nil
 }
 
 func (g *ginServer) isHttpHeaderSignature(ht reflect.Type) bool {
@@ -340,10 +670,10 @@ func (g *ginServer) assignHandler(inOutParam *actionInOutParams) gin.HandlerFunc
 				var e interface{}
 				if reflect.TypeOf(err).Implements(errInterface) {
 					e = errors.WithStack(err.(error)).Error()
-					g.defaultResponse(ctx, nil, &internalError{errors.WithStack(err.(error))})
+					g.render(ctx, http.StatusOK, nil, &internalError{errors.WithStack(err.(error))})
 				} else {
 					e = err
-					g.defaultResponse(ctx, nil, &internalError{fmt.Errorf("%v", err)})
+					g.render(ctx, http.StatusOK, nil, &internalError{fmt.Errorf("%v", err)})
 				}
 				log.Errorf("调用Service服务遇到了问题:(%s;%s/%s) %v",
 					inOutParam.ReqMethod, inOutParam.ResourceName, inOutParam.ActionName,
@@ -351,6 +681,24 @@ func (g *ginServer) assignHandler(inOutParam *actionInOutParams) gin.HandlerFunc
 				PrintStack()
 			}
 		}()
+		// 请求的语言, 由 Translate 使用
+		ctx.Set(translatorKey, g.i18n.find(ctx))
+		// 响应的编码, 由 Respond 使用
+		codec, ok := g.codecs.negotiate(ctx.GetHeader("Accept"))
+		if rc, isChecker := codec.(resultChecker); ok && isChecker && inOutParam.Result != nil {
+			ok = rc.supports(inOutParam.Result)
+		}
+		if !ok && inOutParam.Stream {
+			// SSE 请求的 Accept 为 text/event-stream, 事件和错误都使用默认的编码
+			codec, ok = g.codecs.defaultCodec(), true
+		}
+		if !ok {
+			ctx.Set(codecKey, g.codecs.defaultCodec())
+			ctx.Abort()
+			g.render(ctx, http.StatusNotAcceptable, nil, &notAcceptable{accept: ctx.GetHeader("Accept")})
+			return
+		}
+		ctx.Set(codecKey, codec)
 		// gin框架会自动判断绑定的类型，这里只要区分是否含有Query和body内的绑定。
 		log.Debugf("开始调用: Method: %s; %s/%s", inOutParam.ReqMethod, inOutParam.ResourceName, inOutParam.ActionName)
 		var (
@@ -363,6 +711,10 @@ func (g *ginServer) assignHandler(inOutParam *actionInOutParams) gin.HandlerFunc
 			paramsLen += 1
 		}
 
+		if inOutParam.HasPath {
+			paramsLen += 1
+		}
+
 		if inOutParam.HasQuery {
 			paramsLen += 1
 		}
@@ -371,24 +723,64 @@ func (g *ginServer) assignHandler(inOutParam *actionInOutParams) gin.HandlerFunc
 			paramsLen += 1
 		}
 
+		if inOutParam.Socket != nil {
+			paramsLen += 1
+		}
+
+		if inOutParam.Upload != nil {
+			paramsLen += 1
+		}
+
 		inParams = make([]reflect.Value, paramsLen)
 
 		parentCtx := ctx.Request.Context()
 		if parentCtx.Done() == nil {
 			parentCtx = ctx
 		}
+		if inOutParam.Stream {
+			parentCtx = withLastEventID(parentCtx, ctx.Request)
+		}
+
+		if inOutParam.Interceptor != nil {
+			if re := inOutParam.Interceptor.Before(parentCtx, inOutParam.Info); re != nil {
+				ctx.Abort()
+				g.render(ctx, http.StatusOK, nil, re)
+				return
+			}
+		}
+
+		if re := limitUpload(ctx, inOutParam.UploadLimit); re != nil {
+			ctx.Abort()
+			g.render(ctx, http.StatusRequestEntityTooLarge, nil, re)
+			return
+		}
 
 		inParams[0] = reflect.ValueOf(parentCtx)
 		if inOutParam.HasHeader {
 			inParams[inOutParam.HeaderIndex] = reflect.ValueOf(ctx.Request.Header)
 		}
 
+		if inOutParam.HasPath {
+			u := reflect.New(inOutParam.Path)
+			err = ctx.BindUri(u.Interface())
+			if err != nil {
+				ctx.Abort()
+				g.bindError(ctx, "failed to bind params in path", err, u.Interface())
+				return
+			}
+
+			if inOutParam.PathKind != reflect.Ptr {
+				u = u.Elem()
+			}
+			inParams[inOutParam.PathIndex] = u
+		}
+
 		if inOutParam.HasQuery {
 			q := reflect.New(inOutParam.Query)
 			err = ctx.BindQuery(q.Interface())
 			if err != nil {
 				ctx.Abort()
-				ctx.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "failed to bind params in query", "error": err.Error()})
+				g.bindError(ctx, "failed to bind params in query", err, q.Interface())
 				return
 			}
 
@@ -400,10 +792,15 @@ func (g *ginServer) assignHandler(inOutParam *actionInOutParams) gin.HandlerFunc
 
 		if inOutParam.HasBody {
 			b := reflect.New(inOutParam.Body)
-			err = ctx.Bind(b.Interface())
+			if _, isProto := b.Interface().(proto.Message); !isProto && ctx.ContentType() == MIMEProtobuf {
+				err = errors.Errorf("%s 不是 proto.Message", inOutParam.Body)
+			} else if err = ctx.ShouldBind(b.Interface()); err != nil {
+				// ctx.Bind 会写入400, 请求体超过限制时需要返回413
+				_ = ctx.Error(err).SetType(gin.ErrorTypeBind)
+			}
 			if err != nil {
 				ctx.Abort()
-				ctx.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "failed to bind params in body", "error": err.Error()})
+				g.bindError(ctx, "failed to bind params in body", err, b.Interface())
 				return
 			}
 
@@ -413,6 +810,21 @@ func (g *ginServer) assignHandler(inOutParam *actionInOutParams) gin.HandlerFunc
 			inParams[inOutParam.BodyIndex] = b
 		}
 
+		if inOutParam.Upload != nil {
+			u, err := newUpload(ctx.Request)
+			if err != nil {
+				ctx.Abort()
+				g.bindError(ctx, "failed to bind params in body", err, nil)
+				return
+			}
+			inParams[inOutParam.UploadIndex] = reflect.ValueOf(u)
+		}
+
+		if inOutParam.Socket != nil {
+			g.socket(ctx, parentCtx, inParams, inOutParam)
+			return
+		}
+
 		log.Debugf("Call Params: %d, %+v", len(inParams), inParams)
 		ret := inOutParam.Fn.Call(inParams)
 		log.Debugf("End Fn.Call, in: %v, out: %v", inParams, ret)
@@ -431,102 +843,152 @@ func (g *ginServer) assignHandler(inOutParam *actionInOutParams) gin.HandlerFunc
 			iResp = ret[1].Interface()
 		}
 
-		if iResp == nil {
-			g.defaultResponse(ctx, result, nil)
-			return
+		var re Err
+		status := http.StatusOK
+		switch e := iResp.(type) {
+		case nil:
+		case payload.Response:
+			// 可以设置响应头和状态码
+			status, re = applyResponse(ctx, e, status)
+		default:
+			re = toErr(e)
 		}
 
-		if re, ok := iResp.(Err); ok {
-			g.defaultResponse(ctx, result, re)
-			return
+		if inOutParam.Interceptor != nil {
+			result, re = inOutParam.Interceptor.After(parentCtx, inOutParam.Info, result, re)
 		}
 
-		// internal error 未定义的错误
-		if re, ok := iResp.(error); ok {
-			g.defaultResponse(ctx, result, &internalError{error: re})
-			return
+		// 实现了 payload.Response 的结果同样可以设置响应头和状态码
+		if r, ok := result.(payload.Response); ok && !isNil(r) {
+			var e Err
+			if status, e = applyResponse(ctx, r, status); re == nil {
+				re = e
+			}
 		}
 
-		panic("unreachable code")
+		// channel 无法编码, 出错时只输出错误
+		if ch := reflect.ValueOf(result); ch.Kind() == reflect.Chan {
+			if re == nil && !ch.IsNil() {
+				g.stream(ctx, parentCtx, status, ch)
+				return
+			}
+			result = nil
+		}
 
+		g.render(ctx, status, result, re)
 	}
 }
 
-func (g *ginServer) defaultResponse(ctx *gin.Context, data interface{}, resp Err) {
-	ret := gin.H{}
-	if resp == nil && data == nil {
-		ctx.JSON(http.StatusOK, gin.H{"code": 200})
-		return
+func toErr(e interface{}) Err {
+	switch e := e.(type) {
+	case nil:
+		return nil
+	case Err:
+		return e
+	case error:
+		// 读取上传的文件超过限制
+		var tl *tooLargeErr
+		if errors.As(e, &tl) {
+			return tl
+		}
+		// internal error 未定义的错误
+		return &internalError{error: e}
+	default:
+		panic("unreachable code")
+	}
+}
+
+// applyResponse 把 payload.Response 的响应头写入响应, GetCode() 为有效的 HTTP 状态码时替换 status
+func applyResponse(ctx *gin.Context, resp payload.Response, status int) (int, Err) {
+	if isNil(resp) {
+		return status, nil
 	}
 
-	if data != nil {
-		ret["result"] = data
+	setHeader(ctx, resp.GetHeader())
+	if code := resp.GetCode(); code >= 100 && code < 600 {
+		status = code
 	}
+	return status, toErr(resp.GetErr())
+}
+
+func isNil(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return v == nil || (rv.Kind() == reflect.Ptr && rv.IsNil())
+}
 
-	var (
-		code    int
-		message string
-		errMsg  string
-	)
-	if resp != nil {
-		code = resp.Code()
-		message = resp.Message()
-		errMsg = resp.Error()
+// bindError 绑定入參失败时返回400, 校验失败的字段在 BindError.Fields 中
+func (g *ginServer) bindError(ctx *gin.Context, message string, err error, obj interface{}) {
+	// 请求体超过限制时返回413
+	var tl *tooLargeErr
+	if errors.As(err, &tl) {
+		g.render(ctx, http.StatusRequestEntityTooLarge, nil, tl)
+		return
 	}
 
-	//setHeader(ctx, header)
+	err = revalidate(err, obj)
+	trans := g.i18n.find(ctx)
+	g.render(ctx, http.StatusBadRequest, nil, &BindError{Msg: message, Err: err, Fields: validationErrors(err, trans)})
+}
 
-	if code > 0 {
-		ret["code"] = code
-	} else if code <= 0 && data != nil {
-		ret["code"] = 200
+func (g *ginServer) render(ctx *gin.Context, status int, data interface{}, resp Err) {
+	renderer := g.cnf.Renderer
+	if renderer == nil {
+		renderer = DefaultRenderer
 	}
+	renderer.Render(ctx, g.httpStatus(status, resp), data, resp)
+}
 
-	if message != "" {
-		ret["message"] = message
+// httpStatus 按照 HTTPStatusErr, Config.StatusCodes, Config.HTTPStatus 的顺序把 Err 转换为 HTTP 状态码
+func (g *ginServer) httpStatus(status int, resp Err) int {
+	if resp == nil {
+		return status
 	}
 
-	if errMsg != "" {
-		ret["error"] = errMsg
+	var se HTTPStatusErr
+	if errors.As(resp, &se) && se.HTTPStatus() > 0 {
+		return se.HTTPStatus()
 	}
 
-	if len(ret) == 0 {
-		ctx.JSON(http.StatusOK, gin.H{"code": 200})
-		return
+	if code, ok := g.cnf.StatusCodes[resp.Code()]; ok {
+		return code
 	}
 
-	ctx.JSON(http.StatusOK, ret)
+	if g.cnf.HTTPStatus && resp.Code() >= http.StatusBadRequest && resp.Code() < 600 {
+		return resp.Code()
+	}
+	return status
 }
 
-//func setHeader(ctx *gin.Context, header http.Header) {
-//	if header != nil {
-//		for k, vs := range header {
-//			for _, v := range vs {
-//				ctx.Header(k, v)
-//			}
-//		}
-//	}
-//}
-
-func (g *ginServer) relativePath(version, resourceName, actionName string) string {
-	if len(g.cnf.UrlPrefix) > 0 {
-		return strings.Join([]string{g.cnf.UrlPrefix, version, resourceName, actionName}, "/")
+// setHeader 追加响应头, 多个 Set-Cookie 都会保留
+func setHeader(ctx *gin.Context, header http.Header) {
+	for k, vs := range header {
+		for _, v := range vs {
+			ctx.Writer.Header().Add(k, v)
+		}
 	}
-
-	return strings.Join([]string{version, resourceName, actionName}, "/")
 }
 
 type serviceMap struct {
+	Binding      bindingKey // 绑定的标识, 用于 Unbind
+	Owner        string     // Go方法, 用于路由冲突的错误信息
 	Method       string
 	RelativePath string
+	Group        []gin.HandlerFunc // 服务级别的中间件
+	Middlewares  []gin.HandlerFunc // action级别的中间件
 	Func         gin.HandlerFunc
+	Params       *actionInOutParams
 }
 
 type actionInOutParams struct {
+	HasPath      bool
 	HasQuery     bool
 	HasBody      bool
 	HasHeader    bool
 	HeaderIndex  int
+	Path         reflect.Type
+	PathKind     reflect.Kind
+	PathIndex    int
+	PathParams   []string
 	Query        reflect.Type
 	QueryKind    reflect.Kind
 	QueryIndex   int
@@ -534,8 +996,20 @@ type actionInOutParams struct {
 	BodyKind     reflect.Kind
 	BodyIndex    int
 	OutParamNum  int
+	Result       reflect.Type
+	Stream       bool         // 返回 <-chan T, 以 SSE 的方式输出
+	Socket       reflect.Type // <-chan In 入參, 以 WebSocket 的方式调用
+	SocketIndex  int
+	Upload       reflect.Type // *Upload 或 io.Reader 入參, 不缓冲的请求体
+	UploadIndex  int
+	UploadLimit  int64 // 请求体的最大字节数, 0 表示不限制
 	ReqMethod    string
 	ResourceName string
 	ActionName   string
+	MethodName   string
+	Version      string
+	RelativePath string
+	Info         *ActionInfo
+	Interceptor  ResourceInterceptor
 	Fn           reflect.Value
 }
@@ -3,16 +3,31 @@ package ginrpc
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"github.com/alphaqiu/ginrpc/middleware/gzip"
 	"github.com/alphaqiu/ginrpc/middleware/not_found"
+	"github.com/alphaqiu/ginrpc/mock/model"
 	"github.com/alphaqiu/ginrpc/mock/request"
 	"github.com/alphaqiu/ginrpc/mock/services/inventory"
+	"github.com/alphaqiu/ginrpc/payload"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/gorilla/websocket"
 	logging "github.com/ipfs/go-log/v2"
+	"github.com/pkg/errors"
 	"io"
+	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"net/http/httputil"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -33,6 +48,8 @@ var (
 		{method: http.MethodPost, url: "/api/v1/inventory/remove?name=tom", body: nil, header: nil},
 		{method: http.MethodGet, url: "/api/v1/inventory/remove?name=jerry", body: nil, header: nil},
 		{method: http.MethodGet, url: "/api/v1/inventory/data?name=octopus", body: nil, header: nil},
+		{method: http.MethodGet, url: "/api/v1/inventory/item/42", body: nil, header: nil},
+		{method: http.MethodDelete, url: "/api/v1/inventory/item/42", body: nil, header: nil},
 		{method: http.MethodGet, url: "/api/v1/inventory/empty?name=octopus", body: nil, header: nil},
 		{method: http.MethodOptions, url: "/api/v1/inventory/empty?name=octopus", body: nil, header: nil},
 		{method: http.MethodPost, url: "/api/v1/inventory/query?name=bruce", body: bytes.NewBufferString(`{"name": "alpha"}`), header: nil},
@@ -49,10 +66,9 @@ func TestGinServer_Bind(t *testing.T) {
 		t.Fatalf("绑定服务失败: %v", err)
 	}
 
-	server := httpServer.(*ginServer)
-	server.router.Use(ginLogging.Log(time.RFC3339, true), recover.Recover(true))
-	server.makeRoutes()
-	server.router.Use(not_found.NotFound(nil), gzip.Gzip(gzip.BestCompression))
+	httpServer.BindPreInterceptor(ginLogging.Log(time.RFC3339, true), recover.Recover(true))
+	httpServer.BindPostInterceptor(not_found.NotFound(nil), gzip.Gzip(gzip.BestCompression))
+	handler := httpServer.Handler()
 
 	for _, r := range requests {
 		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Millisecond)
@@ -60,7 +76,7 @@ func TestGinServer_Bind(t *testing.T) {
 		go func(q chan struct{}) {
 			req := request.NewMockRequest(r.method, r.url, r.body, r.header)
 			w := httptest.NewRecorder()
-			server.router.ServeHTTP(w, req)
+			handler.ServeHTTP(w, req)
 			result, err := httputil.DumpResponse(w.Result(), true)
 			if err != nil {
 				fmt.Printf("请求失败: %+v\n", err)
@@ -79,3 +95,1377 @@ func TestGinServer_Bind(t *testing.T) {
 		cancel()
 	}
 }
+
+func newTestServer(t *testing.T) http.Handler {
+	httpServer := New(nil)
+	if err := httpServer.Bind(&inventory.Inventory{}); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+
+	return httpServer.Handler()
+}
+
+func serve(handler http.Handler, method, url string, body io.Reader, header http.Header) *httptest.ResponseRecorder {
+	req := request.NewMockRequest(method, url, body, header)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestGinServer_OpenAPI(t *testing.T) {
+	server := newTestServer(t)
+	w := serve(server, http.MethodGet, "/api/openapi.json", nil, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	doc := make(map[string]interface{})
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid openapi document: %v", err)
+	}
+
+	paths := doc["paths"].(map[string]interface{})
+	remove := paths["/api/v1/inventory/remove"].(map[string]interface{})
+	if _, ok := remove["get"]; !ok {
+		t.Fatalf("missing GET /api/v1/inventory/remove")
+	}
+	if _, ok := remove["post"]; !ok {
+		t.Fatalf("missing POST /api/v1/inventory/remove")
+	}
+
+	params := remove["get"].(map[string]interface{})["parameters"].([]interface{})
+	if params[0].(map[string]interface{})["name"] != "name" {
+		t.Fatalf("unexpected query params: %v", params)
+	}
+
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	if _, ok := schemas["InventoryModel"]; !ok {
+		t.Fatalf("missing InventoryModel schema: %v", schemas)
+	}
+}
+
+func TestGinServer_Exports(t *testing.T) {
+	server := newTestServer(t)
+	w := serve(server, http.MethodGet, "/api/exports", nil, nil)
+
+	var ret struct {
+		Apis []*ActionInfo `json:"apis"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &ret); err != nil {
+		t.Fatalf("invalid exports: %v", err)
+	}
+
+	var header *ActionInfo
+	for _, info := range ret.Apis {
+		if info.GoMethod == "Header" {
+			header = info
+		}
+	}
+
+	if header == nil || header.Method != http.MethodPost || header.Path != "/api/v1/inventory/header" ||
+		header.Resource != "inventory" || header.Action != "header" || header.Version != "v1" {
+		t.Fatalf("unexpected action info: %+v", header)
+	}
+
+	if len(header.Params) != 3 || header.Params[0].In != ParamInQuery || header.Params[1].Type != "*model.InventoryModel" {
+		t.Fatalf("unexpected params: %+v", header.Params)
+	}
+
+	w = serve(server, http.MethodGet, "/api/exports?format=paths", nil, nil)
+	if !bytes.Contains(w.Body.Bytes(), []byte(`"/api/v1/inventory/header"`)) {
+		t.Fatalf("unexpected paths: %s", w.Body.String())
+	}
+
+	w = serve(server, http.MethodGet, "/api/exports?format=unknown", nil, http.Header{"Accept": []string{MIMEYAML}})
+	if w.Code != http.StatusBadRequest || w.Body.String() != "code: 400\nmessage: unsupported format\nerror: 'unsupported format: unknown'\n" {
+		t.Fatalf("unexpected response: %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestGinServer_PathParams(t *testing.T) {
+	server := newTestServer(t)
+	w := serve(server, http.MethodGet, "/api/v1/inventory/item/42", nil, nil)
+	if w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"result":{"name":"42"}`)) {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+
+	doc := new(openAPIDoc)
+	w = serve(server, http.MethodGet, "/api/openapi.json", nil, nil)
+	if err := json.Unmarshal(w.Body.Bytes(), doc); err != nil {
+		t.Fatalf("invalid openapi document: %v", err)
+	}
+
+	op, ok := doc.Paths["/api/v1/inventory/item/{id}"]["get"]
+	if !ok || len(op.Parameters) != 1 || op.Parameters[0].In != "path" || !op.Parameters[0].Required {
+		t.Fatalf("unexpected openapi operation: %+v", op)
+	}
+}
+
+func TestRouteParser(t *testing.T) {
+	// 无效的 HTTP Method 被忽略
+	parser := NewRouteParser(&Config{MethodPrefixes: map[string]string{"List": "get", "Create": http.MethodPost, "Fetch": "LIST_ALL"}})
+	cases := []struct {
+		method    string
+		verb      string
+		action    string
+		ambiguous string
+	}{
+		{method: "GetRemove", verb: http.MethodGet, action: "remove"},
+		{method: "PutItem", verb: http.MethodPut, action: "item"},
+		{method: "PatchItem", verb: http.MethodPatch, action: "item"},
+		{method: "DeleteItem", verb: http.MethodDelete, action: "item"},
+		{method: "HeadItem", verb: http.MethodHead, action: "item"},
+		{method: "OptionsEmpty", verb: http.MethodOptions, action: "empty"},
+		{method: "ListItems", verb: http.MethodGet, action: "items"},
+		{method: "CreateItem", verb: http.MethodPost, action: "item"},
+		{method: "FetchAll", verb: http.MethodPost, action: "fetchall"},
+		{method: "Getaway", verb: http.MethodGet, action: "away", ambiguous: "Get"},
+		{method: "Optionsx", verb: http.MethodOptions, action: "x", ambiguous: "Options"},
+		{method: "Deleted", verb: http.MethodPost, action: "deleted", ambiguous: "Delete"},
+		{method: "Delete", verb: http.MethodPost, action: "delete"},
+		{method: "Put", verb: http.MethodPost, action: "put"},
+		{method: "Get", verb: http.MethodPost, action: "get"},
+		{method: "Header", verb: http.MethodPost, action: "header", ambiguous: "Head"},
+		{method: "Remove", verb: http.MethodPost, action: "remove"},
+	}
+
+	for _, c := range cases {
+		verb, resource, action := parser.Parse("Inventory", c.method)
+		ambiguous, _ := parser.Ambiguous(c.method)
+		if verb != c.verb || resource != "inventory" || action != c.action || ambiguous != c.ambiguous {
+			t.Errorf("%s: got %s %s/%s ambiguous=%q", c.method, verb, resource, action, ambiguous)
+		}
+	}
+}
+
+func TestNamingStrategy(t *testing.T) {
+	cases := []struct {
+		naming NamingStrategy
+		input  string
+		want   string
+	}{
+		{naming: LowerCaseNaming, input: "InventoryItem", want: "inventoryitem"},
+		{naming: KebabCaseNaming, input: "InventoryItem", want: "inventory-item"},
+		{naming: KebabCaseNaming, input: "RemoveAll", want: "remove-all"},
+		{naming: KebabCaseNaming, input: "HTTPServer", want: "http-server"},
+		{naming: KebabCaseNaming, input: "V2Items", want: "v2-items"},
+		{naming: SnakeCaseNaming, input: "RemoveAllByID", want: "remove_all_by_id"},
+		{naming: CamelCaseNaming, input: "RemoveAll", want: "removeAll"},
+		{naming: CamelCaseNaming, input: "HTTPServer", want: "httpServer"},
+	}
+
+	for _, c := range cases {
+		if got := c.naming.Name(c.input); got != c.want {
+			t.Errorf("%s: got %s, want %s", c.input, got, c.want)
+		}
+	}
+
+	cnf := defaultConfig()
+	cnf.Naming = KebabCaseNaming
+	httpServer := New(cnf)
+	if err := httpServer.Bind(&InventoryItem{}); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+
+	server := httpServer.Handler()
+	w := serve(server, http.MethodGet, "/api/v0/inventory-item/remove-all", nil, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	w = serve(server, http.MethodGet, "/api/exports?format=paths", nil, nil)
+	if !bytes.Contains(w.Body.Bytes(), []byte(`"/api/v0/inventory-item/remove-all"`)) {
+		t.Fatalf("unexpected exports: %s", w.Body.String())
+	}
+}
+
+type InventoryItem struct{}
+
+func (i *InventoryItem) GetRemoveAll(ctx context.Context) error {
+	return nil
+}
+
+type Legacy struct{}
+
+func (l *Legacy) Routes() map[string]RouteSpec {
+	return map[string]RouteSpec{
+		"Purge":  {Method: http.MethodDelete, Path: "/legacy/purge/:id"},
+		"GetOld": {Path: "old-name"},
+		"Sync":   {Method: http.MethodPut},
+	}
+}
+
+func (l *Legacy) Purge(ctx context.Context, path model.ItemPath) error { return nil }
+func (l *Legacy) GetOld(ctx context.Context) error                     { return nil }
+func (l *Legacy) Sync(ctx context.Context) error                       { return nil }
+
+type badRoutes struct{ routes map[string]RouteSpec }
+
+func (b *badRoutes) Routes() map[string]RouteSpec                     { return b.routes }
+func (b *badRoutes) Helper(a string) error                            { return nil }
+func (b *badRoutes) Item(ctx context.Context, p model.ItemPath) error { return nil }
+
+func TestGinServer_RouteOverrides(t *testing.T) {
+	httpServer := New(nil)
+	if err := httpServer.Bind(&Legacy{}); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+
+	server := httpServer.Handler()
+	for _, r := range []struct{ method, url string }{
+		{http.MethodDelete, "/api/legacy/purge/42"},
+		{http.MethodGet, "/api/v0/legacy/old-name"},
+		{http.MethodPut, "/api/v0/legacy/sync"},
+	} {
+		if w := serve(server, r.method, r.url, nil, nil); w.Code != http.StatusOK {
+			t.Errorf("%s %s: unexpected status %d", r.method, r.url, w.Code)
+		}
+	}
+
+	for _, routes := range []map[string]RouteSpec{
+		{"Missing": {Method: http.MethodGet}},
+		{"Helper": {Method: http.MethodGet}},
+		{"Item": {Path: "/item"}},
+		{"Item": {Method: "FETCH-ALL"}},
+	} {
+		if err := New(nil).Bind(&badRoutes{routes: routes}); !errors.Is(err, invalidRouteErr) {
+			t.Errorf("%v: expect invalid route error, got %v", routes, err)
+		}
+	}
+
+	// 无效的 HTTP Method 不影响之后的绑定
+	httpServer = New(nil)
+	server = httpServer.Handler()
+	err := httpServer.Bind(&badRoutes{routes: map[string]RouteSpec{"Item": {Method: "FETCH-ALL"}}})
+	if !errors.Is(err, invalidRouteErr) || !strings.Contains(err.Error(), "badRoutes.Item") {
+		t.Fatalf("expect invalid route error, got %v", err)
+	}
+	if err = httpServer.Bind(&Legacy{}); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+	if w := serve(server, http.MethodGet, "/api/v0/legacy/old-name", nil, nil); w.Code != http.StatusOK {
+		t.Errorf("unexpected status %d", w.Code)
+	}
+}
+
+type clash struct{ routes map[string]RouteSpec }
+
+func (c *clash) Routes() map[string]RouteSpec                        { return c.routes }
+func (c *clash) Remove(ctx context.Context) error                    { return nil }
+func (c *clash) Drop(ctx context.Context) error                      { return nil }
+func (c *clash) GetItem(ctx context.Context, p model.ItemPath) error { return nil }
+
+func TestGinServer_RouteConflicts(t *testing.T) {
+	for _, routes := range []map[string]RouteSpec{
+		{"Drop": {Path: "remove"}},
+		{"Drop": {Method: http.MethodGet, Path: "/exports"}},
+		{"Drop": {Method: http.MethodGet, Path: "/v0/clash/item/:name"}},
+	} {
+		err := New(nil).Bind(&clash{routes: routes})
+		if !errors.Is(err, routeConflictErr) || !strings.Contains(err.Error(), "ginrpc.clash.Drop") {
+			t.Errorf("%v: expect route conflict error, got %v", routes, err)
+		}
+	}
+
+	httpServer := New(nil)
+	if err := httpServer.Bind(&inventory.Inventory{}); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+
+	err := httpServer.Bind(&inventory.Inventory{})
+	if !errors.Is(err, routeConflictErr) || strings.Count(err.Error(), "inventory.Inventory.") != 2 {
+		t.Fatalf("expect route conflict error naming both methods, got %v", err)
+	}
+
+	// 冲突的服务不会注册任何路由
+	if err = httpServer.Bind(&clash{routes: map[string]RouteSpec{"Drop": {Path: "remove"}}}); err == nil {
+		t.Fatalf("expect route conflict error")
+	}
+	if err = httpServer.Bind(&clash{}); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+}
+
+type picky struct{}
+
+func (p *picky) Ignore() []string                       { return []string{"Helper"} }
+func (p *picky) GetName(ctx context.Context) error      { return nil }
+func (p *picky) Helper(name string) string              { return name }
+func (p *picky) Broken(ctx context.Context) string      { return "" }
+func (p *picky) Count(ctx context.Context) (int, error) { return 0, nil }
+
+func TestGinServer_StrictBind(t *testing.T) {
+	if err := New(nil).Bind(&picky{}); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+
+	cnf := defaultConfig()
+	cnf.StrictBind = true
+	err := New(cnf).Bind(&picky{})
+	if !errors.Is(err, rejectedMethodsErr) {
+		t.Fatalf("expect rejected methods error, got %v", err)
+	}
+
+	for _, name := range []string{"Broken", "Count"} {
+		if !strings.Contains(err.Error(), name+"(") {
+			t.Errorf("%s should be reported: %v", name, err)
+		}
+	}
+	if strings.Contains(err.Error(), "Helper") || strings.Contains(err.Error(), "Ignore(") {
+		t.Errorf("ignored methods should not be reported: %v", err)
+	}
+
+	// 有歧义的方法名称
+	if err = New(cnf).Bind(&inventory.Inventory{}); !errors.Is(err, rejectedMethodsErr) || !strings.Contains(err.Error(), "Header(") {
+		t.Fatalf("expect Header to be reported, got %v", err)
+	}
+	notHeader := WithMethodFilter(func(method string) bool { return method != "Header" })
+	if err = New(cnf).Bind(&inventory.Inventory{}, notHeader); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+}
+
+type guarded struct {
+	before []string
+}
+
+type guardErr struct{}
+
+func (e *guardErr) Code() int       { return http.StatusForbidden }
+func (e *guardErr) Message() string { return "forbidden" }
+func (e *guardErr) Error() string   { return "access denied" }
+
+func (g *guarded) Before(ctx context.Context, action *ActionInfo) Err {
+	g.before = append(g.before, action.GoMethod)
+	if action.GoMethod == "Secret" {
+		return &guardErr{}
+	}
+	return nil
+}
+
+func (g *guarded) After(ctx context.Context, action *ActionInfo, result interface{}, err Err) (interface{}, Err) {
+	if action.GoMethod == "GetName" {
+		return &model.InventoryModel{Name: "replaced"}, nil
+	}
+	return result, err
+}
+
+func (g *guarded) Secret(ctx context.Context) error { panic("must not be called") }
+func (g *guarded) GetName(ctx context.Context) (*model.InventoryModel, error) {
+	return nil, errors.New("replaced by After")
+}
+
+func TestGinServer_ResourceInterceptor(t *testing.T) {
+	svc := &guarded{}
+	httpServer := New(nil)
+	if err := httpServer.Bind(svc); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+
+	server := httpServer.Handler()
+
+	// Before/After 是拦截器, 不作为服务接口绑定
+	var ret struct {
+		Apis []*ActionInfo `json:"apis"`
+	}
+	if err := json.Unmarshal(serve(server, http.MethodGet, "/api/exports", nil, nil).Body.Bytes(), &ret); err != nil {
+		t.Fatalf("invalid exports: %v", err)
+	}
+	if len(ret.Apis) != 2 {
+		t.Fatalf("unexpected exports: %d apis", len(ret.Apis))
+	}
+	for _, info := range ret.Apis {
+		if info.GoMethod == "Before" || info.GoMethod == "After" {
+			t.Fatalf("interceptor methods should not be exported: %+v", info)
+		}
+	}
+	if w := serve(server, http.MethodPost, "/api/v0/guarded/before", nil, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("interceptor methods should not be routed: %d %s", w.Code, w.Body.String())
+	}
+
+	w := serve(server, http.MethodPost, "/api/v0/guarded/secret", nil, nil)
+	if !bytes.Contains(w.Body.Bytes(), []byte(`"code":403`)) {
+		t.Fatalf("expect rejected by Before: %s", w.Body.String())
+	}
+
+	w = serve(server, http.MethodGet, "/api/v0/guarded/name", nil, nil)
+	if !bytes.Contains(w.Body.Bytes(), []byte(`"result":{"name":"replaced"}`)) || bytes.Contains(w.Body.Bytes(), []byte(`"error"`)) {
+		t.Fatalf("expect replaced by After: %s", w.Body.String())
+	}
+
+	if len(svc.before) != 2 {
+		t.Fatalf("unexpected Before calls: %v", svc.before)
+	}
+}
+
+type admin struct{}
+
+func (a *admin) Middlewares() []gin.HandlerFunc {
+	return []gin.HandlerFunc{func(c *gin.Context) {
+		if c.GetHeader("X-Admin") != "yes" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"code": 401})
+			return
+		}
+		c.Next()
+	}}
+}
+
+func (a *admin) ActionMiddlewares() map[string][]gin.HandlerFunc {
+	return map[string][]gin.HandlerFunc{
+		"GetStats": {func(c *gin.Context) { c.Header("X-Action", "stats") }},
+	}
+}
+
+func (a *admin) GetStats(ctx context.Context) error { return nil }
+func (a *admin) GetUsers(ctx context.Context) error { return nil }
+
+func TestGinServer_ServiceMiddlewares(t *testing.T) {
+	httpServer := New(nil)
+	if err := httpServer.Bind(&admin{}); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+	if err := httpServer.Bind(&inventory.Inventory{}); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+
+	server := httpServer.Handler()
+	if w := serve(server, http.MethodGet, "/api/v0/admin/stats", nil, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expect unauthorized, got %d", w.Code)
+	}
+
+	w := serve(server, http.MethodGet, "/api/v0/admin/stats", nil, http.Header{"X-Admin": []string{"yes"}})
+	if w.Code != http.StatusOK || w.Header().Get("X-Action") != "stats" {
+		t.Fatalf("unexpected response: %d %v", w.Code, w.Header())
+	}
+
+	w = serve(server, http.MethodGet, "/api/v0/admin/users", nil, http.Header{"X-Admin": []string{"yes"}})
+	if w.Code != http.StatusOK || len(w.Header().Get("X-Action")) > 0 {
+		t.Fatalf("action middleware leaked: %d %v", w.Code, w.Header())
+	}
+
+	if w = serve(server, http.MethodGet, "/api/v1/inventory/item/42", nil, nil); w.Code != http.StatusOK {
+		t.Fatalf("service middleware leaked to other services: %d", w.Code)
+	}
+}
+
+func TestGinServer_BindOptions(t *testing.T) {
+	httpServer := New(nil)
+	onlyGet := WithMethodFilter(func(method string) bool { return strings.HasPrefix(method, "Get") })
+	if err := httpServer.Bind(&inventory.Inventory{}, WithName("tenant-a"), onlyGet); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+
+	tagged := func(c *gin.Context) { c.Header("X-Tenant", "b") }
+	err := httpServer.Bind(&inventory.Inventory{},
+		WithName("tenant-b"), WithVersion("v2"), WithPrefix("/rpc"), onlyGet,
+		WithActionMiddleware("GetItem", tagged))
+	if err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+
+	if err = httpServer.Bind(&inventory.Inventory{}, WithActionMiddleware("Missing", tagged)); !errors.Is(err, invalidMiddlewareErr) {
+		t.Fatalf("expect invalid middleware error, got %v", err)
+	}
+
+	server := httpServer.Handler()
+	if w := serve(server, http.MethodGet, "/api/v1/tenant-a/item/1", nil, nil); w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	w := serve(server, http.MethodGet, "/rpc/v2/tenant-b/item/1", nil, nil)
+	if w.Code != http.StatusOK || w.Header().Get("X-Tenant") != "b" {
+		t.Fatalf("unexpected response: %d %v", w.Code, w.Header())
+	}
+
+	if w = serve(server, http.MethodPost, "/api/v1/tenant-a/add", nil, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("filtered method should not be bound: %d", w.Code)
+	}
+}
+
+func TestGinServer_Mount(t *testing.T) {
+	httpServer := New(nil)
+	if err := httpServer.Bind(&inventory.Inventory{}); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+	httpServer.BindPreInterceptor(func(c *gin.Context) { c.Header("X-Ginrpc", "1") })
+
+	engine := gin.New()
+	engine.GET("/health", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	httpServer.Mount(engine)
+	httpServer.Mount(engine.Group("/ext"))
+
+	for _, url := range []string{"/api/v1/inventory/item/42", "/ext/api/v1/inventory/item/42", "/api/exports"} {
+		w := serve(engine, http.MethodGet, url, nil, nil)
+		if w.Code != http.StatusOK || w.Header().Get("X-Ginrpc") != "1" {
+			t.Errorf("%s: unexpected response %d %v", url, w.Code, w.Header())
+		}
+	}
+
+	w := serve(engine, http.MethodGet, "/health", nil, nil)
+	if w.Code != http.StatusOK || len(w.Header().Get("X-Ginrpc")) > 0 {
+		t.Fatalf("pre interceptors should only apply to mounted routes: %d %v", w.Code, w.Header())
+	}
+}
+
+type slow struct{ started, release chan struct{} }
+
+func (s *slow) Wait(ctx context.Context) error {
+	close(s.started)
+	<-s.release
+	return nil
+}
+
+func TestGinServer_Unbind(t *testing.T) {
+	httpServer := New(nil)
+	server := httpServer.Handler()
+
+	svc := &slow{started: make(chan struct{}), release: make(chan struct{})}
+	if err := httpServer.Bind(svc); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+	if w := serve(server, http.MethodGet, "/api/exports?format=paths", nil, nil); !strings.Contains(w.Body.String(), "/api/v0/slow/wait") {
+		t.Fatalf("exports should contain bound service: %s", w.Body.String())
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- serve(server, http.MethodPost, "/api/v0/slow/wait", nil, nil) }()
+	<-svc.started
+
+	if err := httpServer.Unbind(svc); err != nil {
+		t.Fatalf("解绑服务失败: %v", err)
+	}
+	if w := serve(server, http.MethodGet, "/api/exports?format=paths", nil, nil); strings.Contains(w.Body.String(), "/api/v0/slow/wait") {
+		t.Fatalf("exports should not contain unbound service: %s", w.Body.String())
+	}
+	if w := serve(server, http.MethodPost, "/api/v0/slow/wait", nil, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("unbound route should be removed: %d", w.Code)
+	}
+
+	// 解绑之前开始的请求正常完成
+	close(svc.release)
+	if w := <-done; w.Code != http.StatusOK {
+		t.Fatalf("in-flight request should complete: %d", w.Code)
+	}
+
+	if err := httpServer.Unbind(svc); !errors.Is(err, notBoundErr) {
+		t.Fatalf("expect not bound error, got %v", err)
+	}
+
+	// 解绑之后路由可以再次绑定
+	if err := httpServer.Bind(&slow{}); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+}
+
+func TestGinServer_UnbindTenant(t *testing.T) {
+	httpServer := New(nil)
+	server := httpServer.Handler()
+
+	// 零大小的结构体, 不同实例的地址可能相同
+	tenantA, tenantB := &inventory.Inventory{}, &inventory.Inventory{}
+	if err := httpServer.Bind(tenantA, WithName("tenant-a")); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+	if err := httpServer.Bind(tenantB, WithName("tenant-b")); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+	// 同一实例挂载在另一个前缀
+	if err := httpServer.Bind(tenantB, WithName("tenant-b"), WithPrefix("/internal")); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+
+	if err := httpServer.Unbind(tenantA); !errors.Is(err, notBoundErr) {
+		t.Fatalf("expect not bound error, got %v", err)
+	}
+	if err := httpServer.Unbind(tenantA, WithName("tenant-a")); err != nil {
+		t.Fatalf("解绑服务失败: %v", err)
+	}
+	if err := httpServer.Unbind(tenantB, WithName("tenant-b"), WithPrefix("/internal")); err != nil {
+		t.Fatalf("解绑服务失败: %v", err)
+	}
+
+	for url, status := range map[string]int{
+		"/api/v1/tenant-a/item/1":      http.StatusNotFound,
+		"/internal/v1/tenant-b/item/1": http.StatusNotFound,
+		"/api/v1/tenant-b/item/1":      http.StatusOK,
+	} {
+		if w := serve(server, http.MethodGet, url, nil, nil); w.Code != status {
+			t.Errorf("%s: unexpected status %d", url, w.Code)
+		}
+	}
+}
+
+type Signup struct {
+	Name     string `json:"name" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+	Confirm  string `json:"confirm"`
+	Code     string `json:"code" binding:"omitempty,sku"`
+}
+
+type accounts struct{}
+
+func (a *accounts) Register(ctx context.Context, signup Signup) error { return nil }
+
+func registerSKU(t *testing.T) {
+	err := RegisterValidation("sku", func(fl validator.FieldLevel) bool {
+		return strings.HasPrefix(fl.Field().String(), "SKU-")
+	})
+	if err != nil {
+		t.Fatalf("注册校验器失败: %v", err)
+	}
+}
+
+func TestGinServer_Validation(t *testing.T) {
+	registerSKU(t)
+	err := RegisterStructValidation(func(sl validator.StructLevel) {
+		if s := sl.Current().Interface().(Signup); s.Confirm != s.Password {
+			sl.ReportError(s.Confirm, "confirm", "Confirm", "eqfield", "password")
+		}
+	}, Signup{})
+	if err != nil {
+		t.Fatalf("注册校验器失败: %v", err)
+	}
+
+	httpServer := New(nil)
+	if err = httpServer.Bind(&accounts{}); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+	server := httpServer.Handler()
+
+	body := bytes.NewBufferString(`{"password":"123","confirm":"1234","code":"abc"}`)
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	w := serve(server, http.MethodPost, "/api/v0/accounts/register", body, header)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	var ret struct {
+		Code   int               `json:"code"`
+		Errors []ValidationError `json:"errors"`
+	}
+	if err = json.Unmarshal(w.Body.Bytes(), &ret); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+
+	got := make(map[string]ValidationError)
+	for _, fe := range ret.Errors {
+		got[fe.Field] = fe
+	}
+	for field, tag := range map[string]string{"name": "required", "password": "min", "code": "sku", "confirm": "eqfield"} {
+		if fe, ok := got[field]; !ok || fe.Tag != tag || len(fe.Message) == 0 {
+			t.Errorf("%s: unexpected validation error %+v in %s", field, fe, w.Body.String())
+		}
+	}
+	if got["password"].Param != "6" {
+		t.Errorf("unexpected param: %+v", got["password"])
+	}
+
+	body = bytes.NewBufferString(`{"name":"tom","password":"123456","confirm":"123456","code":"SKU-1"}`)
+	if w = serve(server, http.MethodPost, "/api/v0/accounts/register", body, header); w.Code != http.StatusOK {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestGinServer_Localization(t *testing.T) {
+	registerSKU(t)
+	cnf := defaultConfig()
+	cnf.Messages = map[string]map[string]string{
+		"zh": {"forbidden": "禁止访问", "validation.sku": "{0}必须以SKU-开头"},
+	}
+	httpServer := New(cnf)
+	for _, svc := range []interface{}{&accounts{}, &guarded{}} {
+		if err := httpServer.Bind(svc); err != nil {
+			t.Fatalf("绑定服务失败: %v", err)
+		}
+	}
+	server := httpServer.Handler()
+
+	type envelope struct {
+		Message string            `json:"message"`
+		Errors  []ValidationError `json:"errors"`
+	}
+	register := func(header http.Header) envelope {
+		header.Set("Content-Type", "application/json")
+		body := bytes.NewBufferString(`{"password":"123456","confirm":"123456","code":"abc"}`)
+		w := serve(server, http.MethodPost, "/api/v0/accounts/register", body, header)
+		var ret envelope
+		if err := json.Unmarshal(w.Body.Bytes(), &ret); err != nil || len(ret.Errors) != 2 {
+			t.Fatalf("unexpected response: %v %s", err, w.Body.String())
+		}
+		return ret
+	}
+
+	ret := register(http.Header{"X-Language": []string{"zh"}, "Accept-Language": []string{"en"}})
+	if ret.Message != "请求体绑定失败" || ret.Errors[0].Message != "name为必填字段" || ret.Errors[1].Message != "code必须以SKU-开头" {
+		t.Errorf("unexpected zh response: %+v", ret)
+	}
+
+	ret = register(http.Header{"Accept-Language": []string{"fr;q=0.9, zh-CN;q=0.8, en;q=0.1"}})
+	if ret.Message != "请求体绑定失败" {
+		t.Errorf("unexpected Accept-Language response: %+v", ret)
+	}
+
+	ret = register(http.Header{})
+	if ret.Message != "failed to bind params in body" || ret.Errors[0].Message != "name is a required field" ||
+		ret.Errors[1].Message != "code must satisfy sku" {
+		t.Errorf("unexpected default response: %+v", ret)
+	}
+
+	w := serve(server, http.MethodPost, "/api/v0/guarded/secret", nil, http.Header{"Accept-Language": []string{"zh-CN,zh;q=0.9"}})
+	if !strings.Contains(w.Body.String(), `"message":"禁止访问"`) {
+		t.Errorf("Err.Message() should be translated: %s", w.Body.String())
+	}
+}
+
+type boom struct{}
+
+func (b *boom) Explode(ctx context.Context) error { panic("boom") }
+
+func TestGinServer_Renderer(t *testing.T) {
+	registerSKU(t)
+	cnf := defaultConfig()
+	cnf.Renderer = ResponseRendererFunc(func(ctx *gin.Context, status int, result interface{}, err Err) {
+		ret := gin.H{"data": result}
+		if err != nil && err.Code() != 0 {
+			ret["errors"] = []string{fmt.Sprintf("%d:%s", err.Code(), Translate(ctx, err.Message()))}
+			if be, ok := err.(*BindError); ok {
+				ret["fields"] = len(be.Fields)
+			}
+		}
+		ctx.JSON(status, ret)
+	})
+
+	httpServer := New(cnf)
+	for _, svc := range []interface{}{&inventory.Inventory{}, &accounts{}, &guarded{}, &boom{}} {
+		if err := httpServer.Bind(svc); err != nil {
+			t.Fatalf("绑定服务失败: %v", err)
+		}
+	}
+	server := httpServer.Handler()
+
+	header := http.Header{"Content-Type": []string{"application/json"}, "X-Language": []string{"zh"}}
+	for _, c := range []struct {
+		method, url string
+		status      int
+		want        string
+	}{
+		{http.MethodGet, "/api/v1/inventory/item/42", http.StatusOK, `{"data":{"name":"42"}}`},
+		{http.MethodPost, "/api/v0/accounts/register", http.StatusBadRequest, `{"data":null,"errors":["400:请求体绑定失败"],"fields":2}`},
+		{http.MethodPost, "/api/v0/guarded/secret", http.StatusOK, `{"data":null,"errors":["403:forbidden"]}`},
+		{http.MethodPost, "/api/v0/boom/explode", http.StatusOK, `{"data":null,"errors":["500:未知的服务器错误"]}`},
+		{http.MethodGet, "/api/exports?format=unknown", http.StatusBadRequest, `{"data":null,"errors":["400:unsupported format"]}`},
+	} {
+		w := serve(server, c.method, c.url, bytes.NewBufferString(`{}`), header)
+		if w.Code != c.status || w.Body.String() != c.want {
+			t.Errorf("%s %s: unexpected response %d %s", c.method, c.url, w.Code, w.Body.String())
+		}
+	}
+}
+
+type codeErr struct{ code, status int }
+
+func (e *codeErr) Code() int       { return e.code }
+func (e *codeErr) Message() string { return "order error" }
+func (e *codeErr) Error() string   { return fmt.Sprintf("order error %d", e.code) }
+
+type statusErr struct{ codeErr }
+
+func (e *statusErr) HTTPStatus() int { return e.status }
+
+type orders struct{}
+
+func (o *orders) Conflict(ctx context.Context) error { return &codeErr{code: 10001} }
+func (o *orders) Gone(ctx context.Context) error     { return &codeErr{code: http.StatusNotFound} }
+func (o *orders) Teapot(ctx context.Context) error {
+	return &statusErr{codeErr{code: 1, status: http.StatusTeapot}}
+}
+func (o *orders) Fail(ctx context.Context) error { return errors.New("fail") }
+
+func TestGinServer_HTTPStatus(t *testing.T) {
+	for _, c := range []struct {
+		mode bool
+		want map[string]int
+	}{
+		{false, map[string]int{"conflict": http.StatusConflict, "gone": http.StatusOK, "teapot": http.StatusTeapot, "fail": http.StatusOK}},
+		{true, map[string]int{"conflict": http.StatusConflict, "gone": http.StatusNotFound, "teapot": http.StatusTeapot, "fail": http.StatusInternalServerError}},
+	} {
+		cnf := defaultConfig()
+		cnf.HTTPStatus = c.mode
+		cnf.StatusCodes = map[int]int{10001: http.StatusConflict}
+		httpServer := New(cnf)
+		if err := httpServer.Bind(&orders{}); err != nil {
+			t.Fatalf("绑定服务失败: %v", err)
+		}
+
+		server := httpServer.Handler()
+		for action, status := range c.want {
+			w := serve(server, http.MethodPost, "/api/v0/orders/"+action, nil, nil)
+			if w.Code != status || !strings.Contains(w.Body.String(), `"code":`) {
+				t.Errorf("mode %v, %s: unexpected response %d %s", c.mode, action, w.Code, w.Body.String())
+			}
+		}
+	}
+}
+
+type profile struct {
+	payload.DefaultResponse
+	Name string `json:"name"`
+}
+
+type session struct{}
+
+func (s *session) Login(ctx context.Context) payload.Response {
+	return &payload.DefaultResponse{Code: http.StatusCreated, Header: http.Header{"Set-Cookie": []string{"a=1", "b=2"}}}
+}
+
+func (s *session) Deny(ctx context.Context) payload.Response {
+	return &payload.DefaultResponse{Code: http.StatusForbidden, Err: &guardErr{}}
+}
+
+func (s *session) Nothing(ctx context.Context) payload.Response { return nil }
+
+func (s *session) GetProfile(ctx context.Context) (*profile, error) {
+	p := &profile{Name: "tom"}
+	p.Header = http.Header{"X-Cache": []string{"hit"}}
+	return p, nil
+}
+
+func TestGinServer_PayloadResponse(t *testing.T) {
+	httpServer := New(nil)
+	if err := httpServer.Bind(&session{}); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+	server := httpServer.Handler()
+
+	w := serve(server, http.MethodPost, "/api/v0/session/login", nil, nil)
+	if w.Code != http.StatusCreated || len(w.Result().Cookies()) != 2 {
+		t.Errorf("unexpected login response: %d %v", w.Code, w.Header())
+	}
+
+	w = serve(server, http.MethodPost, "/api/v0/session/deny", nil, nil)
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), `"message":"forbidden"`) {
+		t.Errorf("unexpected deny response: %d %s", w.Code, w.Body.String())
+	}
+
+	w = serve(server, http.MethodPost, "/api/v0/session/nothing", nil, nil)
+	if w.Code != http.StatusOK || w.Body.String() != `{"code":200}` {
+		t.Errorf("unexpected nothing response: %d %s", w.Code, w.Body.String())
+	}
+
+	w = serve(server, http.MethodGet, "/api/v0/session/profile", nil, nil)
+	if w.Header().Get("X-Cache") != "hit" || w.Body.String() != `{"code":200,"result":{"name":"tom"}}` {
+		t.Errorf("unexpected profile response: %v %s", w.Header(), w.Body.String())
+	}
+}
+
+type plainCodec struct{}
+
+func (plainCodec) ContentTypes() []string                     { return []string{"text/plain"} }
+func (plainCodec) Marshal(v interface{}) ([]byte, error)      { return []byte(fmt.Sprint(v)), nil }
+func (plainCodec) Unmarshal(data []byte, v interface{}) error { return errors.New("unsupported") }
+
+func TestGinServer_ContentNegotiation(t *testing.T) {
+	cnf := defaultConfig()
+	cnf.Codecs = []Codec{plainCodec{}}
+	httpServer := New(cnf)
+	if err := httpServer.Bind(&inventory.Inventory{}); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+	server := httpServer.Handler()
+
+	get := func(accept string) *httptest.ResponseRecorder {
+		return serve(server, http.MethodGet, "/api/v1/inventory/item/42", nil, http.Header{"Accept": []string{accept}})
+	}
+
+	w := get("application/msgpack")
+	if w.Header().Get("Content-Type") != MIMEMsgPack {
+		t.Errorf("unexpected content type: %s", w.Header().Get("Content-Type"))
+	}
+	var ret struct {
+		Code   int                  `json:"code"`
+		Result model.InventoryModel `json:"result"`
+	}
+	if err := MsgPackCodec.Unmarshal(w.Body.Bytes(), &ret); err != nil || ret.Code != 200 || ret.Result.Name != "42" {
+		t.Errorf("unexpected msgpack response: %v %+v", err, ret)
+	}
+
+	for accept, want := range map[string]string{
+		"":                     `{"code":200,"result":{"name":"42"}}`,
+		"text/html, */*;q=0.1": `{"code":200,"result":{"name":"42"}}`,
+		// 浏览器的 Accept 中 XML 排在 text/html 之后, 使用 JSON
+		"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8": `{"code":200,"result":{"name":"42"}}`,
+		"application/xml": xml.Header + "<response><code>200</code><result><name>42</name></result></response>",
+		"application/json;q=0, application/msgpack;q=0, application/*": "<code>200</code>",
+		"application/x-yaml": "code: 200\nresult:\n  name: \"42\"\n",
+		"text/plain":         "map[code:200 result:",
+		"text/html":          `{"code":406,"error":"unsupported Accept: text/html","message":"not acceptable"}`,
+	} {
+		if w = get(accept); !strings.Contains(w.Body.String(), want) {
+			t.Errorf("%q: unexpected response %s", accept, w.Body.String())
+		}
+	}
+
+	// XML 和 YAML 的字段名称, omitempty 和顺序与 JSON 一致
+	type userModel struct {
+		UserID int    `json:"user_id"`
+		Nick   string `json:"nick,omitempty"`
+	}
+	h := gin.H{"message": "ok", "result": userModel{UserID: 7}, "code": 200}
+	if data, err := XMLCodec.Marshal(h); err != nil || string(data) != xml.Header+"<response><code>200</code><result><user_id>7</user_id></result><message>ok</message></response>" {
+		t.Errorf("unexpected xml: %v %s", err, data)
+	}
+	if data, err := YAMLCodec.Marshal(h); err != nil || string(data) != "code: 200\nresult:\n  user_id: 7\nmessage: ok\n" {
+		t.Errorf("unexpected yaml: %v %s", err, data)
+	}
+
+	if w = get("text/html"); w.Code != http.StatusNotAcceptable || !strings.HasPrefix(w.Header().Get("Content-Type"), MIMEJSON) {
+		t.Errorf("unexpected 406 response: %d %v", w.Code, w.Header())
+	}
+}
+
+type Point struct {
+	X     int32  `protobuf:"varint,1,opt,name=x,proto3" json:"x,omitempty"`
+	Label string `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
+}
+
+func (m *Point) Reset()         { *m = Point{} }
+func (m *Point) String() string { return proto.CompactTextString(m) }
+func (*Point) ProtoMessage()    {}
+
+func init() {
+	proto.RegisterType((*Point)(nil), "ginrpc.test.Point")
+}
+
+type geo struct{}
+
+func (g *geo) Move(ctx context.Context, p *Point) (*Point, error) {
+	if p.X < 0 {
+		return nil, &guardErr{}
+	}
+	return &Point{X: p.X + 1, Label: p.Label}, nil
+}
+
+func TestGinServer_Protobuf(t *testing.T) {
+	httpServer := New(nil)
+	for _, svc := range []interface{}{&geo{}, &inventory.Inventory{}} {
+		if err := httpServer.Bind(svc); err != nil {
+			t.Fatalf("绑定服务失败: %v", err)
+		}
+	}
+	server := httpServer.Handler()
+
+	header := http.Header{"Content-Type": []string{MIMEProtobuf}, "Accept": []string{MIMEProtobuf}}
+	move := func(p *Point) (*httptest.ResponseRecorder, *payload.Envelope) {
+		body, _ := proto.Marshal(p)
+		w := serve(server, http.MethodPost, "/api/v0/geo/move", bytes.NewReader(body), header)
+		env := new(payload.Envelope)
+		if err := proto.Unmarshal(w.Body.Bytes(), env); err != nil {
+			t.Fatalf("invalid envelope: %v", err)
+		}
+		return w, env
+	}
+
+	w, env := move(&Point{X: 1, Label: "a"})
+	result := new(Point)
+	if err := ptypes.UnmarshalAny(env.Result, result); err != nil || env.Code != 200 || result.X != 2 || result.Label != "a" {
+		t.Fatalf("unexpected response: %v %v %v", err, env, result)
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), MIMEProtobuf) {
+		t.Errorf("unexpected content type: %s", w.Header().Get("Content-Type"))
+	}
+
+	if _, env = move(&Point{X: -1}); env.Code != http.StatusForbidden || env.Message != "forbidden" || env.Result != nil {
+		t.Errorf("unexpected error envelope: %v", env)
+	}
+
+	// JSON 请求和响应不受影响
+	w = serve(server, http.MethodPost, "/api/v0/geo/move", bytes.NewBufferString(`{"x":1}`), http.Header{"Content-Type": []string{MIMEJSON}})
+	if w.Body.String() != `{"code":200,"result":{"x":2}}` {
+		t.Errorf("unexpected json response: %s", w.Body.String())
+	}
+
+	// 结果不是 proto.Message 时无法以 protobuf 编码
+	if w = serve(server, http.MethodGet, "/api/v1/inventory/item/42", nil, header); w.Code != http.StatusNotAcceptable {
+		t.Errorf("unexpected status: %d", w.Code)
+	}
+
+	w = serve(server, http.MethodPost, "/api/v1/inventory/add", bytes.NewReader([]byte{8, 1}), header)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status: %d %s", w.Code, w.Body.String())
+	}
+}
+
+type tick struct {
+	Seq int `json:"seq"`
+}
+
+func (t tick) EventName() string { return "tick" }
+
+func (t tick) EventID() string { return strconv.Itoa(t.Seq) }
+
+type TicksQuery struct {
+	Count int `form:"count"`
+	Delay int `form:"delay"`
+}
+
+type progress struct{}
+
+func (p *progress) GetTicks(ctx context.Context, q *TicksQuery) (<-chan tick, error) {
+	if q.Count <= 0 {
+		return nil, &guardErr{}
+	}
+
+	start, _ := strconv.Atoi(LastEventID(ctx))
+	ch := make(chan tick)
+	go func() {
+		defer close(ch)
+		time.Sleep(time.Duration(q.Delay) * time.Millisecond)
+		for seq := start + 1; seq <= start+q.Count; seq++ {
+			select {
+			case ch <- tick{Seq: seq}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func TestGinServer_ServerSentEvents(t *testing.T) {
+	httpServer := New(&Config{UrlPrefix: "/api", SSEKeepAlive: 5 * time.Millisecond})
+	if err := httpServer.Bind(&progress{}); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+	server := httpServer.Handler()
+
+	header := http.Header{"Accept": []string{MIMEEventStream}, HeaderLastEventID: []string{"3"}}
+	w := serve(server, http.MethodGet, "/api/v0/progress/ticks?count=2", nil, header)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != MIMEEventStream {
+		t.Fatalf("unexpected response: %d %v", w.Code, w.Header())
+	}
+	if expected := "id:4\nevent:tick\ndata:{\"seq\":4}\n\nid:5\nevent:tick\ndata:{\"seq\":5}\n\n"; w.Body.String() != expected {
+		t.Errorf("unexpected events: %q", w.Body.String())
+	}
+
+	w = serve(server, http.MethodGet, "/api/v0/progress/ticks?count=1&delay=50", nil, header)
+	if !strings.HasPrefix(w.Body.String(), ": keepalive\n\n") || !strings.HasSuffix(w.Body.String(), "data:{\"seq\":4}\n\n") {
+		t.Errorf("unexpected events: %q", w.Body.String())
+	}
+
+	// 返回错误时按照普通的响应输出
+	w = serve(server, http.MethodGet, "/api/v0/progress/ticks", nil, header)
+	if w.Body.String() != `{"code":403,"error":"access denied","message":"forbidden"}` {
+		t.Errorf("unexpected error response: %s", w.Body.String())
+	}
+}
+
+type ChatPath struct {
+	Room string `uri:"room"`
+}
+
+type chatMessage struct {
+	Text string `json:"text" binding:"required"`
+}
+
+type chat struct{}
+
+func (c *chat) GetTalk(ctx context.Context, path *ChatPath, in <-chan *chatMessage) (<-chan chatMessage, error) {
+	if path.Room == "private" {
+		return nil, &guardErr{}
+	}
+	if path.Room == "crash" {
+		panic("boom")
+	}
+
+	out := make(chan chatMessage)
+	go func() {
+		defer close(out)
+		for msg := range in {
+			if msg.Text == "bye" {
+				return
+			}
+			select {
+			case out <- chatMessage{Text: path.Room + ": " + msg.Text}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+type brokenMessage struct {
+	Text string `json:"text" binding:"nosuchtag"`
+}
+
+// broken 消息的 binding 标签无效, 校验时 panic
+type broken struct{}
+
+func (b *broken) GetTalk(ctx context.Context, in <-chan *brokenMessage) (<-chan chatMessage, error) {
+	out := make(chan chatMessage)
+	go func() {
+		defer close(out)
+		for range in {
+		}
+	}()
+	return out, nil
+}
+
+func TestGinServer_WebSocket(t *testing.T) {
+	httpServer := New(nil)
+	for _, svc := range []interface{}{&chat{}, &broken{}} {
+		if err := httpServer.Bind(svc); err != nil {
+			t.Fatalf("绑定服务失败: %v", err)
+		}
+	}
+	ts := httptest.NewServer(httpServer.Handler())
+	defer ts.Close()
+
+	dial := func(room string) *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/api/v0/chat/talk/"+room, nil)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		return conn
+	}
+	closeCode := func(conn *websocket.Conn) int {
+		_, _, err := conn.ReadMessage()
+		var ce *websocket.CloseError
+		if !errors.As(err, &ce) {
+			t.Fatalf("expect close error, got %v", err)
+		}
+		return ce.Code
+	}
+
+	conn := dial("lobby")
+	defer conn.Close()
+	if err := conn.WriteJSON(chatMessage{Text: "hi"}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	mt, data, err := conn.ReadMessage()
+	if err != nil || mt != websocket.TextMessage || string(data) != `{"text":"lobby: hi"}` {
+		t.Fatalf("unexpected message: %v %d %s", err, mt, data)
+	}
+	_ = conn.WriteJSON(chatMessage{Text: "bye"})
+	if code := closeCode(conn); code != websocket.CloseNormalClosure {
+		t.Errorf("unexpected close code: %d", code)
+	}
+
+	// 校验失败的消息
+	conn = dial("lobby")
+	defer conn.Close()
+	_ = conn.WriteMessage(websocket.TextMessage, []byte(`{}`))
+	if code := closeCode(conn); code != websocket.CloseInvalidFramePayloadData {
+		t.Errorf("unexpected close code: %d", code)
+	}
+
+	// 服务方法返回的错误
+	conn = dial("private")
+	defer conn.Close()
+	if code := closeCode(conn); code != 4000+http.StatusForbidden {
+		t.Errorf("unexpected close code: %d", code)
+	}
+
+	// 升级之后 panic, 通过关闭帧返回
+	conn = dial("crash")
+	defer conn.Close()
+	if code := closeCode(conn); code != websocket.CloseInternalServerErr {
+		t.Errorf("unexpected close code: %d", code)
+	}
+
+	// 解码消息时 panic, 只关闭该连接
+	conn, _, err = websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/api/v0/broken/talk", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.WriteJSON(chatMessage{Text: "hi"})
+	if code := closeCode(conn); code != websocket.CloseInternalServerErr {
+		t.Errorf("unexpected close code: %d", code)
+	}
+
+	// 不是 WebSocket 请求
+	resp, err := http.Get(ts.URL + "/api/v0/chat/talk/lobby")
+	if err != nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected response: %v %v", err, resp)
+	}
+	if resp != nil {
+		_ = resp.Body.Close()
+	}
+}
+
+type AttachmentForm struct {
+	Title  string                  `form:"title" binding:"required"`
+	File   *multipart.FileHeader   `form:"file" binding:"required"`
+	Extras []*multipart.FileHeader `form:"extras"`
+}
+
+type attachment struct {
+	Title string   `json:"title,omitempty"`
+	Files []string `json:"files"`
+	Size  int64    `json:"size"`
+}
+
+type files struct{}
+
+func (f *files) Attach(ctx context.Context, form *AttachmentForm) (*attachment, error) {
+	ret := &attachment{Title: form.Title}
+	for _, fh := range append([]*multipart.FileHeader{form.File}, form.Extras...) {
+		ret.Files = append(ret.Files, fh.Filename)
+		ret.Size += fh.Size
+	}
+	return ret, nil
+}
+
+func (f *files) PutRaw(ctx context.Context, u *Upload) (*attachment, error) {
+	n, err := io.Copy(ioutil.Discard, u)
+	if err != nil {
+		return nil, err
+	}
+	return &attachment{Files: []string{u.Filename}, Size: n}, nil
+}
+
+func TestGinServer_Upload(t *testing.T) {
+	httpServer := New(&Config{UrlPrefix: "/api", MaxUploadSize: 1024})
+	if err := httpServer.Bind(&files{}, WithUploadLimit("PutRaw", 8)); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+	server := httpServer.Handler()
+
+	form := func(title string, files map[string]string) (io.Reader, http.Header) {
+		buf := new(bytes.Buffer)
+		mw := multipart.NewWriter(buf)
+		_ = mw.WriteField("title", title)
+		for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+			if content, ok := files[name]; ok {
+				field := "extras"
+				if name == "a.txt" {
+					field = "file"
+				}
+				fw, _ := mw.CreateFormFile(field, name)
+				_, _ = fw.Write([]byte(content))
+			}
+		}
+		_ = mw.Close()
+		// 隐藏长度, 只能在读取时判断是否超过限制
+		return struct{ io.Reader }{buf}, http.Header{"Content-Type": []string{mw.FormDataContentType()}}
+	}
+
+	body, header := form("docs", map[string]string{"a.txt": "hello", "b.txt": "ab", "c.txt": "c"})
+	w := serve(server, http.MethodPost, "/api/v0/files/attach", body, header)
+	if w.Body.String() != `{"code":200,"result":{"title":"docs","files":["a.txt","b.txt","c.txt"],"size":8}}` {
+		t.Errorf("unexpected response: %s", w.Body.String())
+	}
+
+	body, header = form("docs", map[string]string{"b.txt": "ab"})
+	if w = serve(server, http.MethodPost, "/api/v0/files/attach", body, header); w.Code != http.StatusBadRequest {
+		t.Errorf("expect 400 without file, got %d %s", w.Code, w.Body.String())
+	}
+
+	tooLarge := `{"code":413,"error":"request body exceeds 1024 bytes","message":"request entity too large"}`
+	body, header = form("docs", map[string]string{"a.txt": strings.Repeat("x", 2048)})
+	if w = serve(server, http.MethodPost, "/api/v0/files/attach", body, header); w.Code != http.StatusRequestEntityTooLarge || w.Body.String() != tooLarge {
+		t.Errorf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+
+	raw := http.Header{"Content-Type": []string{"application/octet-stream"}, "Content-Disposition": []string{`attachment; filename="raw.bin"`}}
+	w = serve(server, http.MethodPut, "/api/v0/files/raw", strings.NewReader("12345678"), raw)
+	if w.Body.String() != `{"code":200,"result":{"files":["raw.bin"],"size":8}}` {
+		t.Errorf("unexpected response: %s", w.Body.String())
+	}
+
+	// Content-Length 超过限制时不调用服务方法, 长度未知时在读取时返回错误
+	for _, body := range []io.Reader{strings.NewReader("123456789"), struct{ io.Reader }{strings.NewReader("123456789")}} {
+		w = serve(server, http.MethodPut, "/api/v0/files/raw", body, raw)
+		ret := make(map[string]interface{})
+		_ = json.Unmarshal(w.Body.Bytes(), &ret)
+		if w.Code != http.StatusRequestEntityTooLarge || ret["code"] != float64(413) || ret["error"] != "request body exceeds 8 bytes" {
+			t.Errorf("unexpected response: %d %s", w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestGinServer_StreamWriteTimeout(t *testing.T) {
+	httpServer := New(&Config{UrlPrefix: "/api", SSEKeepAlive: 20 * time.Millisecond})
+	for _, svc := range []interface{}{&progress{}, &chat{}} {
+		if err := httpServer.Bind(svc); err != nil {
+			t.Fatalf("绑定服务失败: %v", err)
+		}
+	}
+	ts := httptest.NewUnstartedServer(httpServer.Handler())
+	ts.Config.WriteTimeout = 100 * time.Millisecond
+	ts.Config.ConnContext = ConnContext
+	ts.Start()
+	defer ts.Close()
+
+	// 推送的时长超过 WriteTimeout
+	resp, err := http.Get(ts.URL + "/api/v0/progress/ticks?count=3&delay=300")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil || !strings.HasSuffix(string(body), "data:{\"seq\":3}\n\n") {
+		t.Errorf("unexpected events: %v %q", err, body)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/api/v0/chat/talk/lobby", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	time.Sleep(300 * time.Millisecond)
+	_ = conn.WriteJSON(chatMessage{Text: "late"})
+	if _, data, err := conn.ReadMessage(); err != nil || string(data) != `{"text":"lobby: late"}` {
+		t.Errorf("unexpected message: %v %s", err, data)
+	}
+}
+
+type hostForm struct {
+	UserName string `json:"user_name" binding:"required"`
+}
+
+type hostSignup struct {
+	Code string `json:"code" binding:"hostonly"`
+}
+
+type hostAccounts struct{}
+
+func (a *hostAccounts) Register(ctx context.Context, signup hostSignup) error { return nil }
+
+func TestGinServer_HostOnlyTag(t *testing.T) {
+	// 只注册在 gin 的校验器上的标签, ginrpc 的校验器无法识别
+	v, err := ginValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = v.RegisterValidation("hostonly", func(fl validator.FieldLevel) bool { return fl.Field().String() == "ok" }); err != nil {
+		t.Fatal(err)
+	}
+
+	httpServer := New(nil)
+	if err = httpServer.Bind(&hostAccounts{}); err != nil {
+		t.Fatalf("绑定服务失败: %v", err)
+	}
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	w := serve(httpServer.Handler(), http.MethodPost, "/api/v0/hostaccounts/register", bytes.NewBufferString(`{"code":"bad"}`), header)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"tag":"hostonly"`) {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestGinServer_HostValidator(t *testing.T) {
+	for i := 0; i < 2; i++ {
+		New(nil)
+	}
+
+	// ginrpc 不修改 gin 的校验器, 宿主应用的 FieldError.Field() 仍为结构体字段名
+	var ves validator.ValidationErrors
+	if err := binding.Validator.ValidateStruct(&hostForm{}); !errors.As(err, &ves) || ves[0].Field() != "UserName" {
+		t.Errorf("unexpected host validation error: %v", err)
+	}
+
+	if err := validatorEngine().Struct(&hostForm{}); !errors.As(err, &ves) || ves[0].Field() != "user_name" {
+		t.Errorf("unexpected ginrpc validation error: %v", err)
+	}
+}
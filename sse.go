@@ -0,0 +1,133 @@
+package ginrpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	MIMEEventStream = "text/event-stream"
+	// HeaderLastEventID 客户端重连时携带的最后一个事件ID
+	HeaderLastEventID = "Last-Event-ID"
+)
+
+type (
+	lastEventIDKey struct{}
+	connKey        struct{}
+)
+
+// EventNamer 流式结果的元素实现该接口时作为 SSE 的 event 字段, 默认为 message
+type EventNamer interface {
+	EventName() string
+}
+
+// EventIdentifier 流式结果的元素实现该接口时作为 SSE 的 id 字段, 客户端重连时通过 Last-Event-ID 带回
+type EventIdentifier interface {
+	EventID() string
+}
+
+// LastEventID 返回客户端重连时的 Last-Event-ID, 用于在返回 <-chan T 的服务方法中断点续传
+func LastEventID(ctx context.Context) string {
+	id, _ := ctx.Value(lastEventIDKey{}).(string)
+	return id
+}
+
+// isStreamResult 返回值为可接收的 channel 时以 SSE 的方式输出
+func isStreamResult(item reflect.Type) bool {
+	return item.Kind() == reflect.Chan && item.ChanDir()&reflect.RecvDir != 0
+}
+
+// stream 逐个输出 ch 的元素直到 channel 关闭或者请求结束, Config.SSEKeepAlive 大于0时定时发送注释保持连接
+func (g *ginServer) stream(ctx *gin.Context, parentCtx context.Context, status int, ch reflect.Value) {
+	header := ctx.Writer.Header()
+	header.Set("Content-Type", MIMEEventStream)
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// 关闭 nginx 的缓冲
+	header.Set("X-Accel-Buffering", "no")
+	// 推送的时长不受 WriteTimeout 限制
+	clearWriteDeadline(ctx.Request.Context())
+	ctx.Status(status)
+	ctx.Writer.Flush()
+
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: ch},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(parentCtx.Done())},
+	}
+	if g.cnf.SSEKeepAlive > 0 {
+		ticker := time.NewTicker(g.cnf.SSEKeepAlive)
+		defer ticker.Stop()
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ticker.C)})
+	}
+
+	codec := g.codecs.defaultCodec()
+	for {
+		chosen, v, ok := reflect.Select(cases)
+		switch chosen {
+		case 0:
+			if !ok {
+				return
+			}
+		case 1:
+			log.Debugf("客户端断开, 结束推送: %s", ctx.Request.URL.Path)
+			return
+		default:
+			if _, err := fmt.Fprint(ctx.Writer, ": keepalive\n\n"); err != nil {
+				return
+			}
+			ctx.Writer.Flush()
+			continue
+		}
+
+		item := v.Interface()
+		data, err := codec.Marshal(item)
+		if err != nil {
+			log.Errorf("编码事件失败: %T, %v", item, err)
+			return
+		}
+
+		event := sse.Event{Event: "message", Data: string(data)}
+		if n, ok := item.(EventNamer); ok {
+			event.Event = n.EventName()
+		}
+		if i, ok := item.(EventIdentifier); ok {
+			event.Id = i.EventID()
+		}
+		if err := event.Render(ctx.Writer); err != nil {
+			log.Debugf("推送事件失败: %v", err)
+			return
+		}
+		ctx.Writer.Flush()
+	}
+}
+
+// withLastEventID 把 Last-Event-ID 放入服务方法的 context
+func withLastEventID(parent context.Context, r *http.Request) context.Context {
+	id := r.Header.Get(HeaderLastEventID)
+	if len(id) == 0 {
+		return parent
+	}
+	return context.WithValue(parent, lastEventIDKey{}, id)
+}
+
+// ConnContext 把连接放入请求的 context, 流式响应通过它取消 http.Server.WriteTimeout.
+// Start 已经设置, 使用 Handler() 自建 http.Server 时需要设置为 http.Server.ConnContext
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connKey{}, c)
+}
+
+// clearWriteDeadline 取消当前连接的写超时, http.Server 处理下一个请求时会重新设置
+func clearWriteDeadline(ctx context.Context) {
+	if c, ok := ctx.Value(connKey{}).(net.Conn); ok {
+		if err := c.SetWriteDeadline(time.Time{}); err != nil {
+			log.Debugf("取消写超时失败: %v", err)
+		}
+	}
+}
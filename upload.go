@@ -0,0 +1,143 @@
+package ginrpc
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/pkg/errors"
+)
+
+const MIMEMultipartForm = binding.MIMEMultipartPOSTForm
+
+var (
+	uploadType     = reflect.TypeOf((*Upload)(nil))
+	readerType     = reflect.TypeOf((*io.Reader)(nil)).Elem()
+	fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+)
+
+// Upload 未经缓冲的请求体, 作为服务方法的入參用于大文件上传, 也可以直接声明为 io.Reader.
+// multipart/form-data 请求时为第一个文件, 其他请求时为整个请求体; 与请求体参数不能同时使用
+type Upload struct {
+	Body        io.Reader
+	ContentType string
+	// Filename multipart 的文件名或者 Content-Disposition 的 filename
+	Filename string
+	// Size 请求体的长度, 未知时为-1, multipart 请求总是-1
+	Size int64
+}
+
+func (u *Upload) Read(p []byte) (int, error) {
+	return u.Body.Read(p)
+}
+
+// isUploadParam *ginrpc.Upload 或者 io.Reader 的入參
+func isUploadParam(t reflect.Type) bool {
+	return t == uploadType || t == readerType
+}
+
+func newUpload(r *http.Request) (*Upload, error) {
+	contentType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != MIMEMultipartForm {
+		u := &Upload{Body: r.Body, ContentType: r.Header.Get("Content-Type"), Size: r.ContentLength}
+		if _, params, err := mime.ParseMediaType(r.Header.Get("Content-Disposition")); err == nil {
+			u.Filename = params["filename"]
+		}
+		return u, nil
+	}
+
+	mr := multipart.NewReader(r.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			return nil, errors.Wrap(err, "multipart 请求中没有文件")
+		}
+		if len(part.FileName()) > 0 {
+			return &Upload{Body: part, ContentType: part.Header.Get("Content-Type"), Filename: part.FileName(), Size: -1}, nil
+		}
+	}
+}
+
+// limitBody 读取超过 n 个字节时返回 *tooLargeErr
+type limitBody struct {
+	io.ReadCloser
+	n     int64
+	limit int64
+}
+
+func (b *limitBody) Read(p []byte) (int, error) {
+	if b.n < 0 {
+		return 0, &tooLargeErr{limit: b.limit}
+	}
+
+	// 多读一个字节判断是否超过限制
+	if int64(len(p)) > b.n+1 {
+		p = p[:b.n+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.n {
+		n, b.n = int(b.n), -1
+		return n, &tooLargeErr{limit: b.limit}
+	}
+	b.n -= int64(n)
+	return n, err
+}
+
+// limitUpload 请求体超过 limit 时返回413, Content-Length 已知时在绑定之前拒绝
+func limitUpload(ctx *gin.Context, limit int64) Err {
+	if limit <= 0 {
+		return nil
+	}
+	if ctx.Request.ContentLength > limit {
+		return &tooLargeErr{limit: limit}
+	}
+	ctx.Request.Body = &limitBody{ReadCloser: ctx.Request.Body, n: limit, limit: limit}
+	return nil
+}
+
+// tooLargeErr 请求体超过了 Config.MaxUploadSize 或者 WithUploadLimit 的限制
+type tooLargeErr struct {
+	limit int64
+}
+
+func (e *tooLargeErr) Code() int {
+	return http.StatusRequestEntityTooLarge
+}
+
+func (e *tooLargeErr) Message() string {
+	return "request entity too large"
+}
+
+func (e *tooLargeErr) Error() string {
+	return "request body exceeds " + strconv.FormatInt(e.limit, 10) + " bytes"
+}
+
+func (e *tooLargeErr) HTTPStatus() int {
+	return http.StatusRequestEntityTooLarge
+}
+
+// hasFileField 结构体包含 *multipart.FileHeader 或 []*multipart.FileHeader 字段时以 multipart/form-data 上传
+func hasFileField(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i).Type
+		if ft.Kind() == reflect.Slice {
+			ft = ft.Elem()
+		}
+		if ft == fileHeaderType || (t.Field(i).Anonymous && hasFileField(ft)) {
+			return true
+		}
+	}
+	return false
+}
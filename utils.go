@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"net/url"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -71,19 +73,66 @@ func makeTls(cnf *HttpTls) *tls.Config {
 	return &tls.Config{Certificates: []tls.Certificate{cert}}
 }
 
-// return http method name/resource name/ action name
-func parseMethodName(resource reflect.Type, method string) (string, string, string) {
-	check := strings.HasPrefix(method, "Get")
-	if check {
-		methodName := strings.ToLower(method[3:])
-		return http.MethodGet, strings.ToLower(resource.Name()), methodName
+// uriParams 返回路径参数结构体中 uri 标签的名称, 按字段定义的顺序生成路由中的 :param
+func uriParams(t reflect.Type) []string {
+	params := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		ft := field.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if field.Anonymous && ft.Kind() == reflect.Struct {
+			params = append(params, uriParams(ft)...)
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("uri"), ",")[0]
+		if len(name) == 0 || name == "-" {
+			continue
+		}
+		params = append(params, name)
+	}
+
+	return params
+}
+
+// qualityValues 按照权重从高到低返回 Accept, Accept-Language 等请求头中的值, q=0 的值在 rejected 中返回
+func qualityValues(header string) (accepted []string, rejected map[string]bool) {
+	type value struct {
+		name string
+		q    float64
 	}
 
-	check = strings.HasPrefix(method, "Options")
-	if check {
-		methodName := strings.ToLower(method[7:])
-		return http.MethodOptions, strings.ToLower(resource.Name()), methodName
+	values := make([]value, 0, 4)
+	rejected = make(map[string]bool)
+	for _, item := range strings.Split(header, ",") {
+		parts := strings.Split(strings.TrimSpace(item), ";")
+		if len(parts[0]) == 0 {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range parts[1:] {
+			if kv := strings.SplitN(strings.TrimSpace(param), "=", 2); len(kv) == 2 && kv[0] == "q" {
+				if v, err := strconv.ParseFloat(kv[1], 64); err == nil {
+					q = v
+				}
+			}
+		}
+
+		if q > 0 {
+			values = append(values, value{name: strings.TrimSpace(parts[0]), q: q})
+		} else {
+			rejected[strings.ToLower(strings.TrimSpace(parts[0]))] = true
+		}
 	}
 
-	return http.MethodPost, strings.ToLower(resource.Name()), strings.ToLower(method)
+	sort.SliceStable(values, func(i, j int) bool { return values[i].q > values[j].q })
+	accepted = make([]string, len(values))
+	for idx, v := range values {
+		accepted[idx] = v.name
+	}
+	return accepted, rejected
 }
@@ -0,0 +1,160 @@
+package ginrpc
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin/binding"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	"github.com/pkg/errors"
+)
+
+// ValidationError 入參校验失败的字段, 在响应的 errors 中返回
+type ValidationError struct {
+	Field   string `json:"field"`
+	Tag     string `json:"tag"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+var (
+	validatorOnce sync.Once
+	validate      *validator.Validate
+	// validationTrans 校验错误的翻译, 按语言在进程内只注册一次
+	validationTrans = make(map[string]ut.Translator)
+)
+
+// validatorEngine 返回 ginrpc 自己的校验器, 不修改 gin 的 binding.Validator.
+// 标签与 gin 一致为 binding, 字段名称按 json, form, uri 标签的顺序取值
+func validatorEngine() *validator.Validate {
+	validatorOnce.Do(func() {
+		validate = validator.New()
+		validate.SetTagName("binding")
+		validate.RegisterTagNameFunc(fieldName)
+
+		uni := ut.New(supportedLocales[DefaultLocale].locale())
+		for name, l := range supportedLocales {
+			if err := uni.AddTranslator(l.locale(), true); err != nil {
+				log.Warnf("添加语言 %s 失败: %v", name, err)
+			}
+			trans, _ := uni.GetTranslator(name)
+			if err := l.validation(validate, trans); err != nil {
+				log.Warnf("注册 %s 的校验错误翻译失败: %v", name, err)
+			}
+			validationTrans[name] = trans
+		}
+	})
+	return validate
+}
+
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "uri"} {
+		name := strings.Split(field.Tag.Get(tag), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if len(name) > 0 {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// ginValidator gin 绑定入參时使用的校验器, 自定义的校验需要同时注册, 否则 gin 无法识别该标签
+func ginValidator() (*validator.Validate, error) {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return nil, errors.Errorf("binding.Validator 不是 go-playground/validator: %T", binding.Validator.Engine())
+	}
+	return v, nil
+}
+
+// RegisterValidation 注册自定义的校验标签, 在 binding 标签中使用, 例如 binding:"required,sku".
+// 同时注册到 gin 的 binding.Validator
+func RegisterValidation(tag string, fn validator.Func) error {
+	v, err := ginValidator()
+	if err != nil {
+		return err
+	}
+	if err = v.RegisterValidation(tag, fn); err != nil {
+		return err
+	}
+	return validatorEngine().RegisterValidation(tag, fn)
+}
+
+// RegisterStructValidation 为结构体注册跨字段的校验, types 为结构体的零值, 例如 model.DateRange{}.
+// 同时注册到 gin 的 binding.Validator
+func RegisterStructValidation(fn validator.StructLevelFunc, types ...interface{}) error {
+	v, err := ginValidator()
+	if err != nil {
+		return err
+	}
+	v.RegisterStructValidation(fn, types...)
+	validatorEngine().RegisterStructValidation(fn, types...)
+	return nil
+}
+
+// revalidate gin 校验失败时使用 ginrpc 的校验器重新校验 obj, 得到以 json 标签命名的字段.
+// 只注册在 gin 的 binding.Validator 上的标签会使 ginrpc 的校验器 panic, 这时返回 gin 的错误
+func revalidate(err error, obj interface{}) (ret error) {
+	var ves validator.ValidationErrors
+	if obj == nil || !errors.As(err, &ves) {
+		return err
+	}
+
+	defer func() {
+		if e := recover(); e != nil {
+			log.Debugf("ginrpc 的校验器无法校验 %T: %v", obj, e)
+			ret = err
+		}
+	}()
+	if verr := validatorEngine().Struct(obj); verr != nil {
+		return verr
+	}
+	return err
+}
+
+// validationErrors 把 validator.ValidationErrors 转换为结构化的字段错误, 其他错误返回nil.
+// message 使用 trans 对应语言的翻译, 自定义标签可以在 Config.Messages 中以 validation.<tag> 为key翻译, {0}为字段, {1}为参数
+func validationErrors(err error, trans ut.Translator) []ValidationError {
+	var ves validator.ValidationErrors
+	if !errors.As(err, &ves) {
+		return nil
+	}
+
+	ret := make([]ValidationError, len(ves))
+	for idx, fe := range ves {
+		ret[idx] = ValidationError{
+			Field:   fe.Field(),
+			Tag:     fe.Tag(),
+			Param:   fe.Param(),
+			Message: validationMessage(fe, trans),
+		}
+	}
+	return ret
+}
+
+func validationMessage(fe validator.FieldError, trans ut.Translator) string {
+	// validator 没有该标签的翻译时返回 fe.Error()
+	if vt, ok := validationTrans[trans.Locale()]; ok {
+		if msg := fe.Translate(vt); msg != fe.Error() {
+			return msg
+		}
+	}
+
+	if msg, err := trans.T("validation."+fe.Tag(), fe.Field(), fe.Param()); err == nil {
+		return msg
+	}
+
+	switch {
+	case fe.Tag() == "required":
+		return fmt.Sprintf("%s is required", fe.Field())
+	case len(fe.Param()) > 0:
+		return fmt.Sprintf("%s must satisfy %s=%s", fe.Field(), fe.Tag(), fe.Param())
+	default:
+		return fmt.Sprintf("%s must satisfy %s", fe.Field(), fe.Tag())
+	}
+}
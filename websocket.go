@@ -0,0 +1,205 @@
+package ginrpc
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// maxCloseReason 关闭帧的 reason 最多123个字节
+	maxCloseReason = 123
+	closeTimeout   = time.Second
+)
+
+// socket 把 WebSocket 连接的消息解码后发送到入參的 channel, 把服务方法返回的 channel 的元素编码后写入连接.
+// 连接在调用服务方法之前升级, 服务方法返回的错误通过关闭帧的状态码返回
+func (g *ginServer) socket(ctx *gin.Context, parentCtx context.Context, inParams []reflect.Value, inOutParam *actionInOutParams) {
+	upgrader := g.cnf.WebSocket
+	if upgrader == nil {
+		upgrader = &websocket.Upgrader{}
+	}
+
+	// 升级失败时 Upgrader 已经返回了错误响应
+	conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		log.Debugf("WebSocket 升级失败: %s, %v", ctx.Request.URL.Path, err)
+		return
+	}
+	defer conn.Close()
+	// 连接已经被接管, 不能再写 HTTP 响应, 通过关闭帧返回错误
+	defer func() {
+		if e := recover(); e != nil {
+			log.Errorf("WebSocket 服务遇到了问题:(%s;%s/%s) %v",
+				inOutParam.ReqMethod, inOutParam.ResourceName, inOutParam.ActionName, e)
+			PrintStack()
+			closeSocket(conn, websocket.CloseInternalServerErr, "unknown server error")
+		}
+	}()
+
+	codec, _ := ctx.Value(codecKey).(Codec)
+	messageType := websocket.BinaryMessage
+	if isTextCodec(codec) {
+		messageType = websocket.TextMessage
+	}
+
+	sockCtx, cancel := context.WithCancel(parentCtx)
+	defer cancel()
+	in := reflect.MakeChan(reflect.ChanOf(reflect.BothDir, inOutParam.Socket.Elem()), 0)
+	inParams[0] = reflect.ValueOf(sockCtx)
+	inParams[inOutParam.SocketIndex] = in
+
+	go func() {
+		// 连接关闭或者消息无效时结束请求
+		defer cancel()
+		defer in.Close()
+		// 解码和校验在该 goroutine 中进行, panic 时只关闭该连接
+		defer func() {
+			if e := recover(); e != nil {
+				log.Errorf("WebSocket 读取消息遇到了问题:(%s;%s/%s) %v",
+					inOutParam.ReqMethod, inOutParam.ResourceName, inOutParam.ActionName, e)
+				PrintStack()
+				closeSocket(conn, websocket.CloseInternalServerErr, "unknown server error")
+			}
+		}()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				log.Debugf("WebSocket 读取结束: %v", err)
+				return
+			}
+
+			v, err := decodeMessage(codec, data, inOutParam.Socket.Elem())
+			if err != nil {
+				closeSocket(conn, websocket.CloseInvalidFramePayloadData, err.Error())
+				return
+			}
+
+			chosen, _, _ := reflect.Select([]reflect.SelectCase{
+				{Dir: reflect.SelectSend, Chan: in, Send: v},
+				{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(sockCtx.Done())},
+			})
+			if chosen == 1 {
+				return
+			}
+		}
+	}()
+
+	ret := inOutParam.Fn.Call(inParams)
+	result, re := ret[0].Interface(), toErr(ret[1].Interface())
+	if inOutParam.Interceptor != nil {
+		result, re = inOutParam.Interceptor.After(sockCtx, inOutParam.Info, result, re)
+	}
+
+	out := reflect.ValueOf(result)
+	if re != nil || out.Kind() != reflect.Chan || out.IsNil() {
+		closeSocket(conn, g.closeCode(re), closeReason(ctx, re))
+		return
+	}
+
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: out},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(sockCtx.Done())},
+	}
+	for {
+		chosen, v, ok := reflect.Select(cases)
+		if chosen == 1 {
+			return
+		}
+		if !ok {
+			closeSocket(conn, websocket.CloseNormalClosure, "")
+			return
+		}
+
+		data, err := codec.Marshal(v.Interface())
+		if err != nil {
+			log.Errorf("编码消息失败: %T, %v", v.Interface(), err)
+			closeSocket(conn, websocket.CloseInternalServerErr, "unknown server error")
+			return
+		}
+		if err = conn.WriteMessage(messageType, data); err != nil {
+			log.Debugf("WebSocket 写入失败: %v", err)
+			return
+		}
+	}
+}
+
+// decodeMessage 使用协商的 Codec 解码消息, 与请求体一样按照 binding 标签校验
+func decodeMessage(codec Codec, data []byte, elem reflect.Type) (reflect.Value, error) {
+	t := elem
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	v := reflect.New(t)
+	if err := codec.Unmarshal(data, v.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+	if err := binding.Validator.ValidateStruct(v.Interface()); err != nil {
+		return reflect.Value{}, revalidate(err, v.Interface())
+	}
+
+	if elem.Kind() != reflect.Ptr {
+		v = v.Elem()
+	}
+	return v, nil
+}
+
+// closeCode 把 Err 转换为关闭帧的状态码, 对应 HTTP 4xx, 5xx 的错误为 4000 + HTTP 状态码, 其他错误为 1011
+func (g *ginServer) closeCode(re Err) int {
+	if re == nil {
+		return websocket.CloseNormalClosure
+	}
+	if _, ok := re.(*internalError); ok {
+		return websocket.CloseInternalServerErr
+	}
+
+	status := g.httpStatus(0, re)
+	if status == 0 {
+		status = re.Code()
+	}
+	if status >= http.StatusBadRequest && status < 600 {
+		return 4000 + status
+	}
+	return websocket.CloseInternalServerErr
+}
+
+func closeReason(ctx *gin.Context, re Err) string {
+	if re == nil {
+		return ""
+	}
+	return Translate(ctx, re.Message())
+}
+
+func closeSocket(conn *websocket.Conn, code int, reason string) {
+	// 按字符截断, 避免截断多字节的翻译
+	for len(reason) > maxCloseReason {
+		_, size := utf8.DecodeLastRuneInString(reason)
+		reason = reason[:len(reason)-size]
+	}
+
+	// WriteControl 可以与读写并发调用
+	msg := websocket.FormatCloseMessage(code, reason)
+	if err := conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(closeTimeout)); err != nil {
+		log.Debugf("WebSocket 关闭失败: %v", err)
+	}
+}
+
+// isSocketParam 可接收的 channel 入參表示以 WebSocket 的方式调用
+func isSocketParam(t reflect.Type) bool {
+	if !isStreamResult(t) {
+		return false
+	}
+
+	elem := t.Elem()
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	return elem.Kind() == reflect.Struct
+}